@@ -1,10 +1,13 @@
 module github.com/johandry/gics
 
-go 1.15
+go 1.16
 
 require (
 	github.com/IBM/go-sdk-core v1.1.0
 	github.com/deepmap/oapi-codegen v1.4.1
+	github.com/hashicorp/hcl/v2 v2.6.0
 	github.com/jarcoal/httpmock v1.0.6
 	github.com/stretchr/testify v1.6.1
+	github.com/zclconf/go-cty v1.2.0
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
 )
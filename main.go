@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
+	"strings"
 
 	"text/tabwriter"
 
@@ -67,6 +72,386 @@ func runSchematicsWorkspaceWithRepo() *schematics.Workspace {
 	return w
 }
 
+func runManifest(args []string) {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	manifest := fs.String("f", "", "workspace manifest file (YAML or JSON)")
+	reuse := fs.Bool("reuse", false, "reuse the existing workspace with the same ID, or name and resource group, instead of creating a new one")
+	fs.Parse(args)
+
+	if len(*manifest) == 0 {
+		printError(fmt.Errorf("the workspace manifest is required, use the flag '-f'"))
+	}
+
+	w, err := schematics.LoadManifest(*manifest)
+	if err != nil {
+		printError(err)
+	}
+	w.SetOutput(os.Stderr)
+
+	if err := w.RunWithOptions(&schematics.RunOptions{ReuseExisting: *reuse}); err != nil {
+		printError(err)
+	}
+
+	fmt.Printf("> Workspace %q (%s) status: %s\n", w.Name, w.ID, w.Status)
+}
+
+func applyManifest(args []string) {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	manifest := fs.String("f", "", "workspace manifest file (YAML or JSON)")
+	dryRun := fs.Bool("dry-run", false, "print the changes without applying them")
+	fs.Parse(args)
+
+	if len(*manifest) == 0 {
+		printError(fmt.Errorf("the workspace manifest is required, use the flag '-f'"))
+	}
+
+	w, err := schematics.LoadManifest(*manifest)
+	if err != nil {
+		printError(err)
+	}
+	w.SetOutput(os.Stderr)
+
+	// Print the changes before they are applied
+	changes, err := w.Preview()
+	if err != nil {
+		printError(err)
+	}
+	for _, c := range changes {
+		fmt.Printf("  %s\n", c)
+	}
+	if *dryRun {
+		if len(changes) == 0 {
+			fmt.Printf("> Workspace %q is up to date\n", w.Name)
+		}
+		return
+	}
+
+	changes, err = w.Reconcile()
+	if err != nil {
+		printError(err)
+	}
+	// Without changes the workspace is applied again only if the last apply
+	// didn't succeed
+	if len(changes) == 0 && w.Status == schematics.WorkspaceStatusActive {
+		fmt.Printf("> Workspace %q (%s) unchanged\n", w.Name, w.ID)
+		return
+	}
+
+	if err := w.WaitReady(); err != nil {
+		printError(err)
+	}
+	act, err := w.Plan()
+	if err != nil {
+		printError(err)
+	}
+	if err := act.Wait(); err != nil {
+		printError(err)
+	}
+
+	if err := w.WaitReady(); err != nil {
+		printError(err)
+	}
+	act, err = w.Apply()
+	if err != nil {
+		printError(err)
+	}
+	if err := act.Wait(); err != nil {
+		printError(err)
+	}
+
+	fmt.Printf("> Workspace %q (%s) applied\n", w.Name, w.ID)
+}
+
+// workspaceFlags adds the flags to identify an existing workspace, by ID or by
+// the name and resource group in a manifest. The returned function gets it
+func workspaceFlags(fs *flag.FlagSet) func() *schematics.Workspace {
+	id := fs.String("id", "", "workspace ID")
+	manifest := fs.String("f", "", "workspace manifest file (YAML or JSON) to find the workspace by name and resource group")
+
+	return func() *schematics.Workspace {
+		if len(*id) != 0 {
+			w, err := schematics.Get(*id)
+			if err != nil {
+				printError(err)
+			}
+			return w
+		}
+
+		if len(*manifest) == 0 {
+			printError(fmt.Errorf("the workspace ID or manifest is required, use the flag '-id' or '-f'"))
+		}
+		m, err := schematics.LoadManifest(*manifest)
+		if err != nil {
+			printError(err)
+		}
+		if len(m.ID) != 0 {
+			w, err := schematics.Get(m.ID)
+			if err != nil {
+				printError(err)
+			}
+			return w
+		}
+		w, err := schematics.Find(m.Name, m.ResourceGroup)
+		if err != nil {
+			printError(err)
+		}
+		return w
+	}
+}
+
+func printOutputs(args []string) {
+	fs := flag.NewFlagSet("outputs", flag.ExitOnError)
+	getWorkspace := workspaceFlags(fs)
+	asJSON := fs.Bool("json", false, "print the outputs values in JSON format")
+	showSensitive := fs.Bool("show-sensitive", false, "print the value of the sensitive outputs")
+	fs.Parse(args)
+
+	w := getWorkspace()
+	outputs, err := w.Outputs(context.Background())
+	if err != nil {
+		printError(err)
+	}
+
+	values := map[string]interface{}{}
+	for _, o := range outputs {
+		values[o.Name] = o.Value
+		if o.Sensitive && !*showSensitive {
+			values[o.Name] = "(sensitive)"
+		}
+	}
+
+	if *asJSON {
+		data, err := json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			printError(err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.TabIndent)
+	fmt.Fprintln(tw, "Name\tType\tValue")
+	for _, o := range outputs {
+		value, _ := json.Marshal(values[o.Name])
+		if s, ok := values[o.Name].(string); ok {
+			value = []byte(s)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", o.Name, o.Type, value)
+	}
+	tw.Flush()
+}
+
+func validateInputs(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	id := fs.String("id", "", "workspace ID, to validate the variables of an existing workspace")
+	manifest := fs.String("f", "", "workspace manifest file (YAML or JSON)")
+	fs.Parse(args)
+
+	var w *schematics.Workspace
+	var err error
+	switch {
+	case len(*id) != 0:
+		w, err = schematics.Get(*id)
+	case len(*manifest) != 0:
+		w, err = schematics.LoadManifest(*manifest)
+	default:
+		err = fmt.Errorf("the workspace ID or manifest is required, use the flag '-id' or '-f'")
+	}
+	if err != nil {
+		printError(err)
+	}
+
+	issues, err := w.ValidateInputs(context.Background())
+	if err != nil {
+		printError(err)
+	}
+	if len(issues) == 0 {
+		fmt.Printf("> Workspace %q variables are valid\n", w.Name)
+		return
+	}
+	for _, issue := range issues {
+		fmt.Printf("  %s\n", issue)
+	}
+	printError(fmt.Errorf("found %d issues in the workspace %q variables", len(issues), w.Name))
+}
+
+func lintManifest(args []string) {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	manifest := fs.String("f", "", "workspace manifest file (YAML or JSON)")
+	fs.Parse(args)
+
+	if len(*manifest) == 0 {
+		printError(fmt.Errorf("the manifest is required, use the flag '-f'"))
+	}
+	w, err := schematics.LoadManifest(*manifest)
+	if err != nil {
+		printError(err)
+	}
+
+	var errors int
+	for _, issue := range w.Lint() {
+		if issue.Severity == schematics.LintError {
+			errors++
+		}
+		fmt.Printf("  %s\n", issue)
+	}
+	if errors != 0 {
+		printError(fmt.Errorf("found %d errors in the workspace %q code", errors, w.Name))
+	}
+	fmt.Printf("> Workspace %q code has no errors\n", w.Name)
+}
+
+func printDocs(args []string) {
+	fs := flag.NewFlagSet("docs", flag.ExitOnError)
+	getWorkspace := workspaceFlags(fs)
+	output := fs.String("o", "", "file to write the documentation, by default it's printed to stdout")
+	fs.Parse(args)
+
+	docs, err := getWorkspace().Docs(context.Background())
+	if err != nil {
+		printError(err)
+	}
+
+	if len(*output) == 0 {
+		fmt.Print(docs)
+		return
+	}
+	if err := ioutil.WriteFile(*output, []byte(docs), 0644); err != nil {
+		printError(err)
+	}
+}
+
+func printResources(args []string) {
+	fs := flag.NewFlagSet("resources", flag.ExitOnError)
+	getWorkspace := workspaceFlags(fs)
+	types := fs.String("type", "", "comma-separated list of resource types to print (i.e. ibm_is_vpc,ibm_is_subnet)")
+	asJSON := fs.Bool("json", false, "print the resources in JSON format")
+	fs.Parse(args)
+
+	var filter []string
+	if len(*types) != 0 {
+		filter = strings.Split(*types, ",")
+	}
+
+	w := getWorkspace()
+	resources, err := w.ListResources(filter...)
+	if err != nil {
+		printError(err)
+	}
+
+	if *asJSON {
+		data, err := json.MarshalIndent(resources, "", "  ")
+		if err != nil {
+			printError(err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	if len(resources) == 0 {
+		fmt.Println("NONE")
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.TabIndent)
+	fmt.Fprintln(tw, "Address\tKind\tProvider\tStatus\tCRN")
+	for _, r := range resources {
+		status := r.Status
+		if r.Tainted {
+			status += " (tainted)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Address, r.Kind, r.Provider, status, r.CRN)
+	}
+	tw.Flush()
+}
+
+func stateCommand(args []string) {
+	if len(args) == 0 {
+		usage()
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("state "+args[0], flag.ExitOnError)
+	getWorkspace := workspaceFlags(fs)
+	fs.Parse(args[1:])
+
+	switch args[0] {
+	case "pull":
+		state, err := getWorkspace().State(context.Background())
+		if err != nil {
+			printError(err)
+		}
+		fmt.Println(string(state.Raw))
+	case "show":
+		if fs.NArg() != 1 {
+			printError(fmt.Errorf("the resource address is required, i.e. 'gics state show -id ID ibm_is_vpc.main'"))
+		}
+		state, err := getWorkspace().State(context.Background())
+		if err != nil {
+			printError(err)
+		}
+		printStateInstance(state, fs.Arg(0))
+	default:
+		usage()
+		os.Exit(1)
+	}
+}
+
+func printStateInstance(state *schematics.State, address string) {
+	instance, ok := state.Instance(address)
+	if !ok {
+		printError(fmt.Errorf("resource instance %q not found in the state", address))
+	}
+
+	// Find the resource of the instance to print the type and name
+	var resource *schematics.StateResource
+	for i, r := range state.Resources {
+		for _, inst := range r.Instances {
+			if inst.Address == instance.Address {
+				resource = &state.Resources[i]
+			}
+		}
+	}
+
+	block := "resource"
+	if resource.Mode == "data" {
+		block = "data"
+	}
+	fmt.Printf("# %s:\n", instance.Address)
+	fmt.Printf("%s %q %q {\n", block, resource.Type, resource.Name)
+	names := make([]string, 0, len(instance.Attributes))
+	for name := range instance.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	for _, name := range names {
+		value, _ := json.Marshal(instance.Attributes[name])
+		fmt.Fprintf(tw, "    %s\t= %s\n", name, value)
+	}
+	tw.Flush()
+	fmt.Println("}")
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: gics COMMAND [FLAGS]\n\n")
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+	tw := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  run -f FILE [-reuse]\tcreate, plan and apply the workspace defined in the manifest FILE")
+	fmt.Fprintln(tw, "  apply -f FILE [-dry-run]\tcreate or update the workspace defined in the manifest FILE, then plan and apply it if changed")
+	fmt.Fprintln(tw, "  validate -id ID | -f FILE\tverify the workspace variables are declared, required and typed as the Terraform code")
+	fmt.Fprintln(tw, "  lint -f FILE\treport the syntax errors and undeclared variables in the code of the manifest FILE")
+	fmt.Fprintln(tw, "  outputs -id ID | -f FILE\tprint the outputs of the workspace")
+	fmt.Fprintln(tw, "  resources -id ID | -f FILE [-type TYPE]\tprint the resources created by the workspace")
+	fmt.Fprintln(tw, "  state pull -id ID | -f FILE\tprint the raw Terraform state of the workspace")
+	fmt.Fprintln(tw, "  state show -id ID | -f FILE ADDRESS\tprint the attributes of a resource instance in the Terraform state")
+	fmt.Fprintln(tw, "  docs -id ID | -f FILE [-o FILE]\trender the workspace README, inputs and outputs in Markdown")
+	fmt.Fprintln(tw, "  list\tlist the existing workspaces")
+	fmt.Fprintln(tw, "  version\tprint the Schematics and GICS versions")
+	fmt.Fprintln(tw, "  demo\tcreate and run a demo workspace")
+	tw.Flush()
+	fmt.Fprintf(os.Stderr, "\nUse 'gics COMMAND -h' to get help about the flags of a command\n")
+}
+
 func printError(err error) {
 	fmt.Printf("[ERROR] %s\n", err)
 	os.Exit(1)
@@ -145,20 +530,44 @@ func printWorkspaceList() {
 }
 
 func main() {
-	if icAPIKey := os.Getenv("IC_API_KEY"); len(icAPIKey) == 0 {
-		printError(fmt.Errorf("[ERROR] GICS requires the IBM Cloud API Key exported in the 'IC_API_KEY' variable"))
+	if len(os.Getenv("IC_API_KEY")) == 0 && len(os.Getenv("IC_IAM_TOKEN")) == 0 {
+		printError(fmt.Errorf("[ERROR] GICS requires the IBM Cloud API Key exported in the 'IC_API_KEY' variable, or an IAM token in 'IC_IAM_TOKEN'"))
 	}
 
-	// printVersions()
-	// printWorkspaceList()
-
-	runSchematicsWorkspaceWithCode()
-	// if err := w.Delete(true); err != nil {
-	// 	printError(err)
-	// }
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(1)
+	}
 
-	// w = runSchematicsWorkspaceWithRepo()
-	// if err := w.Delete(true); err != nil {
-	// 	printError(err)
-	// }
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "run":
+		runManifest(args)
+	case "apply":
+		applyManifest(args)
+	case "validate":
+		validateInputs(args)
+	case "lint":
+		lintManifest(args)
+	case "outputs":
+		printOutputs(args)
+	case "resources":
+		printResources(args)
+	case "state":
+		stateCommand(args)
+	case "docs":
+		printDocs(args)
+	case "list":
+		printWorkspaceList()
+	case "version":
+		printVersions()
+	case "demo":
+		runSchematicsWorkspaceWithCode()
+		// w = runSchematicsWorkspaceWithRepo()
+		// if err := w.Delete(true); err != nil {
+		// 	printError(err)
+		// }
+	default:
+		usage()
+		os.Exit(1)
+	}
 }
@@ -2,15 +2,24 @@ package schematics
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	apiv1 "github.com/johandry/gics/schematics/api/v1"
 )
 
 const (
-	createWorkspaceTimeout = 50
+	createWorkspaceTimeout    = 50
+	planWorkspaceTimeout      = 50
+	applyWorkspaceTimeout     = 50
+	refreshWorkspaceTimeout   = 50
+	waitReadyWorkspaceTimeout = 600
 )
 
+// statusPollInterval is the time to wait between requests for the workspace status
+var statusPollInterval = 5 * time.Second
+
 // Create creates a Schematics Workspace and returns the activity in charge of
 // this task
 func (w *Workspace) Create() (*Activity, error) {
@@ -20,6 +29,20 @@ func (w *Workspace) Create() (*Activity, error) {
 	ctx, cancelFunc := context.WithTimeout(context.Background(), createWorkspaceTimeout*time.Second)
 	defer cancelFunc()
 
+	if err := w.checkVariablesSecrets(); err != nil {
+		return nil, err
+	}
+
+	if len(w.Type) == 0 {
+		w.Type = templateIDDefault
+	}
+
+	if region := w.api().region; len(region) != 0 {
+		if err := w.service.ValidateRegion(ctx, region); err != nil {
+			return nil, err
+		}
+	}
+
 	variables := []apiv1.WorkspaceVariableRequest{}
 	for _, v := range w.Variables {
 		variable := apiv1.WorkspaceVariableRequest{
@@ -33,8 +56,18 @@ func (w *Workspace) Create() (*Activity, error) {
 	}
 	variableStore := apiv1.VariablesRequest(variables)
 
+	envValues := apiv1.EnvVariableRequest{}
+	for _, env := range w.EnvValues {
+		envValue := map[string]interface{}{}
+		for name, value := range env {
+			envValue[name] = value
+		}
+		envValues = append(envValues, envValue)
+	}
+
 	templateData := &apiv1.TemplateData{
 		apiv1.TemplateSourceDataRequest{
+			EnvValues:           &envValues,
 			Folder:              &w.Folder,
 			InitStateFile:       &w.InitStateFile,
 			Type:                &w.Type,
@@ -53,12 +86,16 @@ func (w *Workspace) Create() (*Activity, error) {
 			Url:          &w.GitRepo.URL,
 		}
 	}
+	// A new workspace has no code uploaded yet
+	w.remoteCodeHash = ""
+	w.CodeCommit = ""
+	tags := w.tags()
 	workspaceCreateRequest := apiv1.WorkspaceCreateRequest{
 		Description:   &w.Description,
 		Location:      &w.Location,
 		Name:          &w.Name,
 		ResourceGroup: &w.ResourceGroup,
-		Tags:          &w.Tags,
+		Tags:          &tags,
 		TemplateData:  templateData,
 		TemplateRepo:  templateRepo,
 		Type:          &[]string{w.Type},
@@ -66,7 +103,7 @@ func (w *Workspace) Create() (*Activity, error) {
 
 	params := &apiv1.CreateWorkspaceParams{}
 	body := apiv1.CreateWorkspaceJSONRequestBody(apiv1.CreateWorkspaceJSONBody(workspaceCreateRequest))
-	resp, err := w.service.clientWithResponses.CreateWorkspaceWithResponse(ctx, params, body)
+	resp, err := w.api().clientWithResponses.CreateWorkspaceWithResponse(ctx, params, body)
 	if err != nil {
 		return nil, err
 	}
@@ -75,6 +112,15 @@ func (w *Workspace) Create() (*Activity, error) {
 	}
 	response := resp.JSON201 // WorkspaceResponse
 
+	w.update(response)
+
+	// There isn't an Activity for workspace create, this should return a Nil Activity.
+	// Just keeping it here in case the API change in the future
+	return w.LastActivity(activityNameForCreate)
+}
+
+// update updates the workspace fields with the values returned by the API
+func (w *Workspace) update(response *apiv1.WorkspaceResponse) {
 	if response.CreatedAt != nil {
 		w.CreatedAt = *response.CreatedAt
 	}
@@ -86,16 +132,26 @@ func (w *Workspace) Create() (*Activity, error) {
 	w.Name = stringValue(response.Name)
 	w.ResourceGroup = stringValue(response.ResourceGroup)
 
-	t := *response.Type
-	if len(t) > 0 {
-		w.Type = t[0]
+	if response.Type != nil && len(*response.Type) > 0 {
+		w.Type = (*response.Type)[0]
 	}
 
-	if response.Status != nil {
-		w.Status = WorkspaceStatus(*response.Status)
-	}
+	w.updateStatus(response)
 	if response.Tags != nil {
-		w.Tags = *response.Tags
+		w.Tags = []string{}
+		w.remoteCodeHash = ""
+		w.CodeCommit = ""
+		for _, tag := range *response.Tags {
+			if strings.HasPrefix(tag, codeHashTagPrefix) {
+				w.remoteCodeHash = strings.TrimPrefix(tag, codeHashTagPrefix)
+				continue
+			}
+			if strings.HasPrefix(tag, codeCommitTagPrefix) {
+				w.CodeCommit = strings.TrimPrefix(tag, codeCommitTagPrefix)
+				continue
+			}
+			w.Tags = append(w.Tags, tag)
+		}
 	}
 
 	if response.TemplateRepo != nil {
@@ -106,54 +162,217 @@ func (w *Workspace) Create() (*Activity, error) {
 			Release:      stringValue(response.TemplateRepo.Release),
 			RepoShaValue: stringValue(response.TemplateRepo.RepoShaValue),
 		}
+		w.repoFullURL = stringValue(response.TemplateRepo.FullUrl)
 	}
 
-	// EnvValues *EnvVariableRequest `json:"env_values,omitempty"`
-	if len(*response.TemplateData) > 0 {
-		templateData := *response.TemplateData
-
-		w.Folder = stringValue(templateData[0].Folder)
-		w.UninstallScriptName = stringValue(templateData[0].UninstallScriptName)
-		w.Values = stringValue(templateData[0].Values)
+	if response.TemplateData == nil || len(*response.TemplateData) == 0 {
+		return
+	}
+	templateData := (*response.TemplateData)[0]
+
+	w.templateID = stringValue(templateData.Id)
+	w.Folder = stringValue(templateData.Folder)
+	w.UninstallScriptName = stringValue(templateData.UninstallScriptName)
+	w.Values = stringValue(templateData.Values)
+
+	var envValues []EnvVariable
+	if templateData.EnvValues != nil {
+		for _, env := range *templateData.EnvValues {
+			envValues = append(envValues, EnvVariable{
+				stringValue(env.Name): stringValue(env.Value),
+			})
+		}
+	}
+	w.EnvValues = envValues
 
-		wv := []apiv1.WorkspaceVariableRequest(*templateData[0].Variablestore)
-		var variables []Variable
+	var variables []Variable
+	if templateData.Variablestore != nil {
+		wv := []apiv1.WorkspaceVariableRequest(*templateData.Variablestore)
 		if len(wv) > 0 {
 			variables = []Variable{}
 		}
 		for _, v := range wv {
 			variable := Variable{
-				Name:        *v.Name,
-				Value:       *v.Value,
-				Type:        *v.Type,
-				Description: *v.Description,
-				Secure:      *v.Secure,
+				Name:        stringValue(v.Name),
+				Value:       stringValue(v.Value),
+				Type:        stringValue(v.Type),
+				Description: stringValue(v.Description),
+				Secure:      boolValue(v.Secure),
 			}
 			variables = append(variables, variable)
 		}
-		w.Variables = variables
+	}
+	w.Variables = variables
+}
+
+// updateStatus updates the workspace status, status message and lock with the
+// values returned by the API. The template ID is set if it's unknown, i.e. the
+// workspace was not created or loaded from the API
+func (w *Workspace) updateStatus(response *apiv1.WorkspaceResponse) {
+	if len(w.templateID) == 0 && response.TemplateData != nil && len(*response.TemplateData) != 0 {
+		w.templateID = stringValue((*response.TemplateData)[0].Id)
+	}
+	if response.Status != nil {
+		w.Status = ParseWorkspaceStatus(string(*response.Status))
+	}
+	if response.WorkspaceStatusMsg != nil {
+		w.StatusMessage = stringValue(response.WorkspaceStatusMsg.StatusMsg)
+	}
+	if response.WorkspaceStatus != nil {
+		w.Locked = boolValue(response.WorkspaceStatus.Locked)
+		w.LockedBy = stringValue(response.WorkspaceStatus.LockedBy)
+		w.Frozen = boolValue(response.WorkspaceStatus.Frozen)
+	}
+}
+
+// refreshStatus gets the current status of the workspace from the API
+func (w *Workspace) refreshStatus(ctx context.Context) error {
+	resp, err := w.api().clientWithResponses.GetWorkspaceWithResponse(ctx, w.ID, &apiv1.GetWorkspaceParams{})
+	if err != nil {
+		return err
+	}
+	if code := resp.StatusCode(); code != 200 {
+		return getAPIError("failed to get the workspace status", resp.Body)
+	}
+
+	w.updateStatus(resp.JSON200)
+
+	return nil
+}
+
+// checkAction refreshes the workspace status and returns an error if the given
+// action can't be executed on the workspace in the current status
+func (w *Workspace) checkAction(ctx context.Context, action WorkspaceAction) error {
+	if err := w.refreshStatus(ctx); err != nil {
+		return err
+	}
+	return w.validateAction(action)
+}
+
+// WaitReady waits until the workspace is not busy processing the template or
+// executing an action, so it's ready for the next action. It fails if the
+// workspace template contains errors
+func (w *Workspace) WaitReady() error {
+	ctx, cancelFunc := context.WithTimeout(context.Background(), waitReadyWorkspaceTimeout*time.Second)
+	defer cancelFunc()
+
+	for {
+		if err := w.refreshStatus(ctx); err != nil {
+			return err
+		}
+		if !w.Status.IsBusy() {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timeout waiting for the workspace %q, the status is %s", w.Name, w.Status)
+		case <-time.After(statusPollInterval):
+		}
 	}
 
-	w.Status = WorkspaceStatusInactive
+	if w.Status == WorkspaceStatusTemplateError {
+		return fmt.Errorf("the workspace %q template contains errors. %s", w.Name, w.StatusMessage)
+	}
 
-	// There isn't an Activity for workspace create, this should return a Nil Activity.
-	// Just keeping it here in case the API change in the future
-	return w.LastActivity(activityNameForCreate)
+	return nil
 }
 
 // Plan executes the planning of the Schematics Workspace
 func (w *Workspace) Plan() (*Activity, error) {
 	w.Output = nil
-	// TODO: Complete the Plan method of Workspace
-	return &Activity{}, nil
+
+	// Plan Timeout
+	ctx, cancelFunc := context.WithTimeout(context.Background(), planWorkspaceTimeout*time.Second)
+	defer cancelFunc()
+
+	if err := w.checkAction(ctx, WorkspaceActionPlan); err != nil {
+		return nil, err
+	}
+
+	token, err := w.service.refreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	params := &apiv1.PlanWorkspaceCommandParams{
+		RefreshToken: token,
+	}
+	resp, err := w.api().clientWithResponses.PlanWorkspaceCommandWithResponse(ctx, w.ID, params)
+	if err != nil {
+		return nil, err
+	}
+	if code := resp.StatusCode(); code != 202 {
+		return nil, getAPIError("failed to plan the workspace", resp.Body)
+	}
+	response := resp.JSON202 // WorkspaceActivityPlanResult
+
+	return w.activity(stringValue(response.Activityid))
 }
 
 // Apply executes the applying of the Schematics Workspace. It 'executes' the
 // Terraform code in the workspace
 func (w *Workspace) Apply() (*Activity, error) {
 	w.Output = nil
-	// TODO: Complete the Apply method of Workspace
-	return &Activity{}, nil
+
+	// Apply Timeout
+	ctx, cancelFunc := context.WithTimeout(context.Background(), applyWorkspaceTimeout*time.Second)
+	defer cancelFunc()
+
+	if err := w.checkAction(ctx, WorkspaceActionApply); err != nil {
+		return nil, err
+	}
+
+	token, err := w.service.refreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	params := &apiv1.ApplyWorkspaceCommandParams{
+		RefreshToken: token,
+	}
+	body := apiv1.ApplyWorkspaceCommandJSONRequestBody{}
+	resp, err := w.api().clientWithResponses.ApplyWorkspaceCommandWithResponse(ctx, w.ID, params, body)
+	if err != nil {
+		return nil, err
+	}
+	if code := resp.StatusCode(); code != 202 {
+		return nil, getAPIError("failed to apply the workspace", resp.Body)
+	}
+	response := resp.JSON202 // WorkspaceActivityApplyResult
+
+	return w.activity(stringValue(response.Activityid))
+}
+
+// Refresh executes the refresh of the Schematics Workspace, it updates the
+// Terraform state with the real resources
+func (w *Workspace) Refresh() (*Activity, error) {
+	// Refresh Timeout
+	ctx, cancelFunc := context.WithTimeout(context.Background(), refreshWorkspaceTimeout*time.Second)
+	defer cancelFunc()
+
+	if err := w.checkAction(ctx, WorkspaceActionRefresh); err != nil {
+		return nil, err
+	}
+
+	token, err := w.service.refreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	params := &apiv1.RefreshWorkspaceCommandParams{
+		RefreshToken: token,
+	}
+	resp, err := w.api().clientWithResponses.RefreshWorkspaceCommandWithResponse(ctx, w.ID, params)
+	if err != nil {
+		return nil, err
+	}
+	if code := resp.StatusCode(); code != 202 {
+		return nil, getAPIError("failed to refresh the workspace", resp.Body)
+	}
+	response := resp.JSON202 // WorkspaceActivityRefreshResult
+
+	return w.activity(stringValue(response.Activityid))
 }
 
 // Destroy destroyes the resources created by the Terraform code in the Schematics
@@ -164,7 +383,7 @@ func (w *Workspace) Destroy() (*Activity, error) {
 	if err != nil {
 		return nil, err
 	}
-	if len(resources) == 0 {
+	if countManaged(resources) == 0 {
 		err := w.delete(false)
 		return &NilActivity, err
 	}
@@ -187,7 +406,6 @@ func (w *Workspace) Destroy() (*Activity, error) {
 func (w *Workspace) LastActivity(name string) (*Activity, error) {
 	// Get all the activities of the workspace
 	activities, err := w.LastActivities()
-	// fmt.Printf("[DEBUG] Activities: %+v\n", activities)
 	if err != nil || activities == nil {
 		return &NilActivity, err
 	}
@@ -203,7 +421,7 @@ func (w *Workspace) LastActivity(name string) (*Activity, error) {
 	// Trying to safe some time
 	l := len(activitiesWithName)
 	if l == 0 {
-		return nil, nil
+		return &NilActivity, nil
 	}
 	if l == 1 {
 		return &activitiesWithName[0], nil
@@ -228,9 +446,24 @@ func (w *Workspace) LastActivity(name string) (*Activity, error) {
 	return &activity, nil
 }
 
+// activity returns the workspace activity with the given ID
+func (w *Workspace) activity(id string) (*Activity, error) {
+	if len(id) == 0 {
+		return &NilActivity, nil
+	}
+
+	activity := NewActivity(w.api(), w.ID, &apiv1.WorkspaceActivity{ActionId: &id})
+	if err := activity.refresh(); err != nil {
+		return nil, err
+	}
+	activity.SetOutput(w.logOutput)
+
+	return &activity, nil
+}
+
 // LastActivities returns the last executed activities
 func (w *Workspace) LastActivities() ([]Activity, error) {
-	activities, err := getActivities(w.service, w.ID)
+	activities, err := getActivities(w.api(), w.ID)
 	if err != nil {
 		return nil, err
 	}
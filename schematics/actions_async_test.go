@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/jarcoal/httpmock"
+	apiv1 "github.com/johandry/gics/schematics/api/v1"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -14,7 +15,7 @@ func TestWorkspace_Create(t *testing.T) {
 	workspaceName := "workspace"
 	workspaceID := fmt.Sprintf("%s-2b1cf4ac-348f-49", workspaceName)
 
-	httpmock.RegisterResponder("GET", `=~^https://schematics\.cloud\.ibm\.com/v1/workspaces/([\w-]+)/actions\z`,
+	httpmock.RegisterResponder("GET", `=~^https://us-south\.schematics\.cloud\.ibm\.com/v1/workspaces/([\w-]+)/actions\z`,
 		func(req *http.Request) (*http.Response, error) {
 			// Get the fixture with the following code after getting the Token:
 			// export TOKEN=$(cat .token | jq -r .access_token)
@@ -82,10 +83,12 @@ func TestWorkspace_Create(t *testing.T) {
 			URL:    "https://github.com/IBM/cloud-enterprise-examples",
 			Branch: "master",
 		},
-		CreatedAt: createdTime,
-		CreatedBy: "johandry@gmail.com",
-		Status:    "DRAFT",
-		service:   defaultService,
+		CreatedAt:   createdTime,
+		CreatedBy:   "johandry@gmail.com",
+		Status:      "DRAFT",
+		service:     defaultService,
+		templateID:  "iac-f6ee24a6-1775-42",
+		repoFullURL: "https://github.com/IBM/cloud-enterprise-examples/tree/master/iac/01-getting-started",
 	}
 
 	w := New(workspaceName, "", nil)
@@ -157,3 +160,60 @@ func TestWorkspace_LastActivity(t *testing.T) {
 	assert.Equal(t, expectedAct, act)
 	assert.Equal(t, expectedWorkspace, w)
 }
+
+func TestActivity_Wait(t *testing.T) {
+	workspaceID := "workspace-5c3d2e1f-0a9b-8c"
+	activityID := "8f7e6d5c4b3a29180f7e6d5c4b3a2918"
+	activityURL := fmt.Sprintf("https://schematics.cloud.ibm.com/v1/workspaces/%s/actions/%s", workspaceID, activityID)
+
+	defer func(interval, timeout time.Duration) {
+		statusPollInterval, waitActivityTimeout = interval, timeout
+	}(statusPollInterval, waitActivityTimeout)
+	statusPollInterval = time.Millisecond
+	waitActivityTimeout = 100 * time.Millisecond
+
+	tests := []struct {
+		name     string
+		statuses []string
+		wantErr  bool
+	}{
+		{"completed", []string{"IN PROGRESS", "COMPLETED"}, false},
+		{"failed", []string{"CREATED", "IN PROGRESS", "FAILED"}, true},
+		{"stopped", []string{"IN PROGRESS", "STOPPED"}, true},
+		{"cancelled", []string{"PENDING", "CANCELLED"}, true},
+		{"timeout", []string{"IN PROGRESS"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			httpmock.RegisterResponder("GET", activityURL,
+				func(req *http.Request) (*http.Response, error) {
+					status := tt.statuses[requests]
+					if requests < len(tt.statuses)-1 {
+						requests++
+					}
+					fixture := fmt.Sprintf(`{"action_id":"%s","name":"PLAN","status":"%s","performed_by":"johandry@gmail.com","templates":[{"template_id":"iac-f6ee24a6-1775-42","status":"%s","message":"done"}]}`, activityID, status, status)
+					resp := httpmock.NewStringResponse(200, fixture)
+					resp.Header.Add("Content-Type", "application/json; charset=utf-8")
+					return resp, nil
+				},
+			)
+
+			act := NewActivity(defaultService, workspaceID, &apiv1.WorkspaceActivity{ActionId: &activityID})
+			done := make(chan error)
+			go func() { done <- act.Wait() }()
+
+			select {
+			case err := <-done:
+				if tt.wantErr {
+					assert.Error(t, err, "Wait() should fail when the activity failed")
+				} else {
+					assert.NoError(t, err, "Wait() should not fail when the activity completed")
+				}
+				assert.Equal(t, tt.statuses[len(tt.statuses)-1], act.Status)
+			case <-time.After(3 * time.Second):
+				t.Fatal("Wait() did not return")
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"strings"
 	"time"
 
 	apiv1 "github.com/johandry/gics/schematics/api/v1"
@@ -15,11 +16,25 @@ const (
 	activityNameForDestroy = "DESTROY"
 )
 
+const (
+	// activityStatusCompleted is the status of an activity that finished successfully
+	activityStatusCompleted = "COMPLETED"
+	// activityStatusFailed is the status of an activity that failed
+	activityStatusFailed = "FAILED"
+	// activityStatusStopped is the status of an activity stopped by the user
+	activityStatusStopped = "STOPPED"
+	// activityStatusCancelled is the status of an activity cancelled before it started
+	activityStatusCancelled = "CANCELLED"
+)
+
 const (
 	listWorkspaceActivitiesTimeout  = 50
 	refreshWorkspaceActivityTimeout = 30
 )
 
+// waitActivityTimeout is the maximum time to wait for an activity to finish
+var waitActivityTimeout = time.Hour
+
 // NilActivity is an empty or nil activity that doesn't exists or already finished
 var NilActivity = NewActivity(nil, "", nil)
 
@@ -103,17 +118,13 @@ func getActivities(service *Service, workspaceID string) ([]Activity, error) {
 
 	// No activities found
 	if response.Actions == nil || len(*response.Actions) == 0 {
-		// fmt.Printf("[DEBUG] no Activities for Workspace %s\n", workspaceID)
 		return []Activity{}, nil
 	}
 
-	// fmt.Printf("[DEBUG] all Activities %+v\n", *response.Actions)
-
 	wID := *response.WorkspaceId
 	activities := []Activity{}
 	for _, act := range *response.Actions {
 		activity := NewActivity(service, wID, &act)
-		// fmt.Printf("[DEBUG] appending Activity %+v\n", activity)
 		activities = append(activities, activity)
 	}
 
@@ -165,21 +176,42 @@ func (a *Activity) refresh() error {
 	return nil
 }
 
-// Wait ...
+// Wait waits until the activity is completed, it fails if the activity failed,
+// was stopped or cancelled, or if it doesn't finish before waitActivityTimeout
 func (a *Activity) Wait() error {
 	if a.isNil() {
 		return nil
 	}
 
+	ctx, cancelFunc := context.WithTimeout(context.Background(), waitActivityTimeout)
+	defer cancelFunc()
+
 	for {
 		if err := a.refresh(); err != nil {
 			return err
 		}
-		if a.Status == "DONE" {
+		switch a.Status {
+		case activityStatusCompleted:
 			a.logPrintf("completed. Status: %s", a.Status)
 			return nil
+		case activityStatusFailed, activityStatusStopped, activityStatusCancelled:
+			a.logPrintf("finished. Status: %s", a.Status)
+			result := "failed"
+			if a.Status != activityStatusFailed {
+				result = "was " + strings.ToLower(a.Status)
+			}
+			if len(a.Message) != 0 {
+				return fmt.Errorf("the activity %s %s %s. %s", a.Name, a.ID, result, a.Message)
+			}
+			return fmt.Errorf("the activity %s %s %s", a.Name, a.ID, result)
 		}
 		a.logPrintf("waiting. Status: %s", a.Status)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timeout waiting for the activity %s %s, the status is %s", a.Name, a.ID, a.Status)
+		case <-time.After(statusPollInterval):
+		}
 	}
 }
 
@@ -0,0 +1,200 @@
+package schematics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+)
+
+// maxUploadSize is the maximum size of the code tar accepted by Schematics
+var maxUploadSize int64 = 83886080
+
+// largestFilesCount is the number of largest files reported when the code is
+// too large
+const largestFilesCount = 5
+
+// binaryMagics are the first bytes of the executable binaries for Linux (ELF),
+// macOS (Mach-O) and Windows (PE)
+var binaryMagics = [][]byte{
+	[]byte("\x7fELF"),
+	{0xfe, 0xed, 0xfa, 0xce},
+	{0xfe, 0xed, 0xfa, 0xcf},
+	{0xce, 0xfa, 0xed, 0xfe},
+	{0xcf, 0xfa, 0xed, 0xfe},
+	[]byte("MZ"),
+}
+
+// Exclude excludes the code files matching any of the given patterns, so they
+// are not uploaded. A pattern ending with `/` excludes every file in the
+// directories with that name, i.e. `.terraform/`, any other pattern is matched
+// with path.Match against the file path and the file name, i.e. `*.tfstate`.
+// The patterns are applied to the loaded code and to the code loaded later
+func (w *Workspace) Exclude(patterns ...string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(strings.TrimSuffix(p, "/"), ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q. %s", p, err)
+		}
+	}
+	w.excludes = append(w.excludes, patterns...)
+
+	if len(w.tfCodeFiles) == 0 {
+		return nil
+	}
+	return w.loadTar()
+}
+
+// excludeFiles removes the loaded code files matching the exclude patterns
+func (w *Workspace) excludeFiles() {
+	if len(w.excludes) == 0 {
+		return
+	}
+	for name := range w.tfCodeFiles {
+		if excluded(name, w.excludes) {
+			delete(w.tfCodeFiles, name)
+			delete(w.tfFileModes, name)
+		}
+	}
+}
+
+func excluded(name string, patterns []string) bool {
+	for _, p := range patterns {
+		if strings.HasSuffix(p, "/") {
+			dir := strings.TrimSuffix(p, "/")
+			for _, d := range strings.Split(path.Dir(name), "/") {
+				if ok, _ := path.Match(dir, d); ok {
+					return true
+				}
+			}
+			continue
+		}
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, path.Base(name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckCode verifies the loaded code can be uploaded to Schematics, before
+// sending it. It fails if the tar is larger than the maximum size accepted by
+// Schematics, reporting the largest files, or if the code contains files that
+// should not be uploaded: `.terraform/` directories, Terraform state files or
+// provider binaries. Use Exclude() to exclude these files
+func (w *Workspace) CheckCode() error {
+	if len(w.tfCodeFiles) == 0 {
+		return nil
+	}
+
+	var problems []string
+	for _, name := range sortedKeys(w.tfCodeFiles) {
+		if reason := refusedFile(name, w.tfCodeFiles[name]); len(reason) != 0 {
+			problems = append(problems, fmt.Sprintf("%q %s", name, reason))
+		}
+	}
+
+	size, err := w.tarSize()
+	if err != nil {
+		return err
+	}
+	if size > maxUploadSize {
+		problem := fmt.Sprintf("the code size (%s) is larger than the maximum size accepted by Schematics (%s), the largest files are:", humanSize(size), humanSize(maxUploadSize))
+		for _, name := range w.largestFiles(largestFilesCount) {
+			problem += fmt.Sprintf("\n      %s (%s)", name, humanSize(int64(len(w.tfCodeFiles[name]))))
+		}
+		problems = append(problems, problem)
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("the code of the workspace %q cannot be uploaded:\n  - %s\nRemove these files from the code or exclude them with Exclude(), i.e. `w.Exclude(\".terraform/\", \"*.tfstate\")`, or with the `exclude` list in the manifest", w.Name, strings.Join(problems, "\n  - "))
+}
+
+// refusedFile returns the reason to refuse the upload of the given file, or an
+// empty string if the file can be uploaded
+func refusedFile(name, content string) string {
+	base := path.Base(name)
+	switch {
+	case excluded(name, []string{".terraform/"}):
+		return "is in a .terraform directory, it's created by `terraform init` and Schematics creates its own"
+	case strings.HasSuffix(base, ".tfstate") || strings.HasSuffix(base, ".tfstate.backup"):
+		return "is a Terraform state file, Schematics keeps the state of the workspace"
+	case strings.HasPrefix(base, "terraform-provider-"):
+		return "is a provider binary, Schematics installs the providers"
+	case isBinary(content):
+		return "is an executable binary, Schematics installs the providers and tools"
+	}
+	return ""
+}
+
+func isBinary(content string) bool {
+	for _, magic := range binaryMagics {
+		if strings.HasPrefix(content, string(magic)) {
+			// "MZ" is too short to be sure, the PE binaries have a NUL byte soon
+			if bytes.Equal(magic, []byte("MZ")) && !strings.Contains(prefix(content, 512), "\x00") {
+				continue
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func prefix(s string, n int) string {
+	if len(s) < n {
+		return s
+	}
+	return s[:n]
+}
+
+// tarSize returns the size of the tar of the loaded code
+func (w *Workspace) tarSize() (int64, error) {
+	counter := &countWriter{w: ioutil.Discard}
+	if err := w.writeTar(counter); err != nil {
+		return 0, err
+	}
+	return counter.n, nil
+}
+
+// largestFiles returns the names of the n largest code files, the largest first
+func (w *Workspace) largestFiles(n int) []string {
+	names := sortedKeys(w.tfCodeFiles)
+	sort.SliceStable(names, func(i, j int) bool {
+		return len(w.tfCodeFiles[names[i]]) > len(w.tfCodeFiles[names[j]])
+	})
+	if len(names) > n {
+		names = names[:n]
+	}
+	return names
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func humanSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,78 @@
+package schematics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkspace_CheckCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string][]byte
+		exclude []string
+		wantErr []string
+	}{
+		{"valid", map[string][]byte{"main.tf": []byte(`variable "prefix" {}`), "scripts/init.sh": []byte("#!/bin/sh\n")}, nil, nil},
+		{"terraform dir", map[string][]byte{"main.tf": nil, ".terraform/modules/modules.json": []byte("{}")}, nil, []string{`".terraform/modules/modules.json" is in a .terraform directory`}},
+		{"state files", map[string][]byte{"main.tf": nil, "terraform.tfstate": []byte("{}"), "env/terraform.tfstate.backup": []byte("{}")}, nil, []string{
+			`"env/terraform.tfstate.backup" is a Terraform state file`,
+			`"terraform.tfstate" is a Terraform state file`,
+		}},
+		{"providers", map[string][]byte{"main.tf": nil, "plugins/terraform-provider-ibm_v1.16.1": []byte("#!"), "bin/tool": []byte("\x7fELF\x02\x01\x01")}, nil, []string{
+			`"plugins/terraform-provider-ibm_v1.16.1" is a provider binary`,
+			`"bin/tool" is an executable binary`,
+		}},
+		{"excluded", map[string][]byte{"main.tf": nil, ".terraform/plugins/terraform-provider-ibm": []byte("\x7fELF"), "terraform.tfstate": []byte("{}")}, []string{".terraform/", "*.tfstate"}, nil},
+		{"too large", map[string][]byte{"main.tf": nil, "data/big.json": make([]byte, 2048), "data/small.json": make([]byte, 512)}, nil, []string{
+			"the code size (5.0 KB) is larger than the maximum size accepted by Schematics (3.0 KB), the largest files are:\n      data/big.json (2.0 KB)\n      data/small.json (512 B)\n      main.tf (0 B)",
+		}},
+	}
+
+	defer func(size int64) { maxUploadSize = size }(maxUploadSize)
+	maxUploadSize = 3072
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := New("check", "", nil)
+			if !assert.NoError(t, w.Exclude(tt.exclude...)) || !assert.NoError(t, w.LoadFiles(tt.files)) {
+				return
+			}
+
+			err := w.CheckCode()
+			if len(tt.wantErr) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			if !assert.Error(t, err) {
+				return
+			}
+			for _, want := range tt.wantErr {
+				assert.Contains(t, err.Error(), want)
+			}
+			assert.True(t, strings.HasSuffix(err.Error(), "or with the `exclude` list in the manifest"), "the error should explain how to exclude the files")
+		})
+	}
+}
+
+func TestWorkspace_Exclude(t *testing.T) {
+	w := New("exclude", "", nil)
+	w.LoadFiles(map[string][]byte{
+		"main.tf":                   nil,
+		"terraform.tfstate":         nil,
+		"modules/a/.terraform/x":    nil,
+		"modules/a/main.tf":         nil,
+		"modules/a/test/fixture.tf": nil,
+	})
+
+	assert.NoError(t, w.Exclude(".terraform/", "*.tfstate"))
+	assert.Equal(t, []string{"main.tf", "modules/a/main.tf", "modules/a/test/fixture.tf"}, sortedKeys(w.tfCodeFiles))
+
+	// the patterns apply to the code loaded later
+	assert.NoError(t, w.Exclude("modules/a/test/*"))
+	w.AddFile("modules/a/test/other.tf", nil, 0)
+	assert.Equal(t, []string{"main.tf", "modules/a/main.tf"}, sortedKeys(w.tfCodeFiles))
+
+	assert.Error(t, w.Exclude("[invalid"))
+}
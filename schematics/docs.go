@@ -0,0 +1,153 @@
+package schematics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	apiv1 "github.com/johandry/gics/schematics/api/v1"
+)
+
+const (
+	getWorkspaceReadmeTimeout = 50
+)
+
+// Readme gets the README.md file of the workspace template. If the code is in
+// a Git repository, the README is read from the workspace branch
+func (w *Workspace) Readme(ctx context.Context) (string, error) {
+	// Readme Timeout
+	ctx, cancelFunc := context.WithTimeout(ctx, getWorkspaceReadmeTimeout*time.Second)
+	defer cancelFunc()
+
+	params := &apiv1.GetWorkspaceReadmeParams{}
+	if w.GitRepo != nil && len(w.GitRepo.Branch) != 0 {
+		params.Ref = &w.GitRepo.Branch
+	}
+	resp, err := w.api().clientWithResponses.GetWorkspaceReadmeWithResponse(ctx, w.ID, params)
+	if err != nil {
+		return "", err
+	}
+	if code := resp.StatusCode(); code != 200 {
+		return "", getAPIError("failed to get the workspace README", resp.Body)
+	}
+	if resp.JSON200 == nil {
+		return "", nil
+	}
+
+	return stringValue(resp.JSON200.Readme), nil
+}
+
+// Docs renders the documentation of the workspace in Markdown. It combines the
+// workspace settings, the template README, the input variables with their
+// current values and the current outputs in a single page
+func (w *Workspace) Docs(ctx context.Context) (string, error) {
+	readme, err := w.Readme(ctx)
+	if err != nil {
+		return "", err
+	}
+	decls, err := w.declaredVariables(ctx)
+	if err != nil {
+		return "", err
+	}
+	outputs, err := w.Outputs(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "# %s\n\n", w.Name)
+	if len(w.Description) != 0 {
+		fmt.Fprintf(&buf, "%s\n\n", w.Description)
+	}
+	fmt.Fprintf(&buf, "| Setting | Value |\n|---|---|\n")
+	settings := [][2]string{
+		{"ID", w.ID},
+		{"Status", string(w.Status)},
+		{"Location", w.Location},
+		{"Resource Group", w.ResourceGroup},
+		{"Template Type", w.Type},
+		{"Tags", strings.Join(w.Tags, ", ")},
+	}
+	if w.GitRepo != nil {
+		settings = append(settings, [2]string{"Repository", w.GitRepo.URL})
+		settings = append(settings, [2]string{"Branch", w.GitRepo.Branch})
+	}
+	for _, s := range settings {
+		if len(s[1]) != 0 {
+			fmt.Fprintf(&buf, "| %s | %s |\n", s[0], mdCell(s[1]))
+		}
+	}
+
+	if len(strings.TrimSpace(readme)) != 0 {
+		fmt.Fprintf(&buf, "\n## README\n\n%s\n", strings.TrimSpace(demoteHeadings(readme)))
+	}
+
+	fmt.Fprintf(&buf, "\n## Inputs\n\n")
+	if len(decls) == 0 {
+		fmt.Fprintf(&buf, "No inputs.\n")
+	} else {
+		values := map[string]Variable{}
+		for _, v := range w.Variables {
+			values[v.Name] = v
+		}
+		fmt.Fprintf(&buf, "| Name | Type | Default | Value | Description |\n|---|---|---|---|---|\n")
+		for _, d := range decls {
+			value := ""
+			if v, ok := values[d.Name]; ok {
+				value = "`" + v.Value + "`"
+				if v.Secure {
+					value = sensitiveValue
+				}
+			}
+			def := ""
+			if d.HasDefault {
+				def = "`" + d.Default + "`"
+			}
+			fmt.Fprintf(&buf, "| %s | `%s` | %s | %s | %s |\n", d.Name, typeString(d.Type), mdCell(def), mdCell(value), mdCell(d.Description))
+		}
+	}
+
+	fmt.Fprintf(&buf, "\n## Outputs\n\n")
+	if len(outputs) == 0 {
+		fmt.Fprintf(&buf, "No outputs.\n")
+	} else {
+		fmt.Fprintf(&buf, "| Name | Type | Value |\n|---|---|---|\n")
+		for _, o := range outputs {
+			value := sensitiveValue
+			if !o.Sensitive {
+				data, _ := json.Marshal(o.Value)
+				value = "`" + string(data) + "`"
+			}
+			fmt.Fprintf(&buf, "| %s | `%s` | %s |\n", o.Name, o.Type, mdCell(value))
+		}
+	}
+
+	return buf.String(), nil
+}
+
+// demoteHeadings moves the Markdown headings one level down, so the README
+// headings are under the workspace title. The code blocks are not modified
+func demoteHeadings(md string) string {
+	lines := strings.Split(md, "\n")
+	inCode := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if !inCode && strings.HasPrefix(line, "#") {
+			lines[i] = "#" + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// mdCell escapes a value to be in a Markdown table cell
+func mdCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.ReplaceAll(strings.TrimSpace(s), "\n", "<br>")
+}
@@ -0,0 +1,65 @@
+package schematics
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkspace_Docs(t *testing.T) {
+	workspaceName := "docs"
+	workspaceID := fmt.Sprintf("%s-7d8e9f0a-1b2c-3d", workspaceName)
+	templateID := "iac-a7b8c9d0-1e2f-3a"
+	baseURL := fmt.Sprintf("https://us-south.schematics.cloud.ibm.com/v1/workspaces/%s", workspaceID)
+
+	// Get the fixture with the following code after getting the Token:
+	// export TOKEN=$(cat .token | jq -r .access_token)
+	// export WID=
+	// curl -X GET "https://schematics.cloud.ibm.com/v1/workspaces/$WID/templates/readme" -H "Authorization: Bearer $TOKEN" -H 'Accept: application/json'
+	httpmock.RegisterResponder("GET", baseURL+"/templates/readme",
+		jsonResponder(200, `{"readme":"# GICS Demo\n\nCreates a resource group.\n\n## Usage\n\n`+"```"+`\n# not a heading\n`+"```"+`\n"}`),
+	)
+	httpmock.RegisterResponder("GET", fmt.Sprintf("%s/template_data/%s/values_metadata", baseURL, templateID),
+		jsonResponder(200, `[{"name":"prefix","type":"string","description":"prefix of the resources"},{"name":"api_key","type":"string"},{"name":"region","type":"string","default":"us-south"}]`),
+	)
+	httpmock.RegisterResponder("GET", baseURL+"/output_values",
+		jsonResponder(200, `[{"folder":".","id":"iac-a7b8c9d0-1e2f-3a","output_values":[{"name":{"sensitive":false,"type":"string","value":"gics-demo-group"},"token":{"sensitive":true,"type":"string","value":"secret"}}],"value_type":"terraform_v0.13"}]`),
+	)
+
+	w := New(workspaceName, "", nil)
+	w.ID = workspaceID
+	w.templateID = templateID
+	w.Description = "GICS demo | docs"
+	w.Location = "us-south"
+	w.Status = WorkspaceStatusActive
+	w.AddVar("prefix", "gics-demo", "", "", false)
+	w.AddVar("api_key", "secret", "", "", true)
+
+	docs, err := w.Docs(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	expected := "# docs\n\n" +
+		"GICS demo | docs\n\n" +
+		"| Setting | Value |\n|---|---|\n" +
+		"| ID | docs-7d8e9f0a-1b2c-3d |\n" +
+		"| Status | ACTIVE |\n" +
+		"| Location | us-south |\n" +
+		"| Template Type | terraform_v0.13 |\n" +
+		"\n## README\n\n" +
+		"## GICS Demo\n\nCreates a resource group.\n\n### Usage\n\n```\n# not a heading\n```\n" +
+		"\n## Inputs\n\n" +
+		"| Name | Type | Default | Value | Description |\n|---|---|---|---|---|\n" +
+		"| prefix | `string` |  | `gics-demo` | prefix of the resources |\n" +
+		"| api_key | `string` |  | (sensitive) |  |\n" +
+		"| region | `string` | `us-south` |  |  |\n" +
+		"\n## Outputs\n\n" +
+		"| Name | Type | Value |\n|---|---|---|\n" +
+		"| name | `string` | `\"gics-demo-group\"` |\n" +
+		"| token | `string` | (sensitive) |\n"
+	assert.Equal(t, expected, docs)
+}
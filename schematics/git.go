@@ -0,0 +1,118 @@
+package schematics
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// LoadGitRef tar and loads the code of a local Git checkout at the given ref
+// (branch, tag or commit) to the workspace. Only the files committed in the ref
+// are loaded, the local changes are ignored. If repoPath is a subdirectory of
+// the checkout, only the files in that directory are loaded. The commit SHA is
+// stored in the workspace tags when the code is uploaded, see CodeCommit
+func (w *Workspace) LoadGitRef(repoPath, ref string) error {
+	if len(ref) == 0 {
+		ref = "HEAD"
+	}
+
+	commit, err := git(repoPath, "rev-parse", "--verify", ref+"^{commit}")
+	if err != nil {
+		return fmt.Errorf("failed to find the ref %q in %q. %s", ref, repoPath, err)
+	}
+	prefix, err := git(repoPath, "rev-parse", "--show-prefix")
+	if err != nil {
+		return fmt.Errorf("failed to find the Git checkout of %q. %s", repoPath, err)
+	}
+
+	top, err := git(repoPath, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return fmt.Errorf("failed to find the Git checkout of %q. %s", repoPath, err)
+	}
+
+	// The tree of the subdirectory, so the files are relative to repoPath
+	archive, err := git(top, "archive", "--format=tar", commit+":"+prefix)
+	if err != nil {
+		return fmt.Errorf("failed to archive the ref %q of %q. %s", ref, repoPath, err)
+	}
+	files, modes, err := readTarFiles(strings.NewReader(archive))
+	if err != nil {
+		return fmt.Errorf("failed to read the archive of the ref %q of %q. %s", ref, repoPath, err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("not found any file in the ref %q of %q", ref, repoPath)
+	}
+
+	w.tfCodeFiles = files
+	w.tfFileModes = modes
+	w.vendored = nil
+	if err := w.loadTar(); err != nil {
+		return err
+	}
+
+	if absPath, err := filepath.Abs(repoPath); err == nil {
+		w.codePath = absPath
+	}
+	w.codeCommit = commit
+
+	return nil
+}
+
+// git executes the git command in the given directory and returns the output
+// without the trailing new line
+func git(dir string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); len(msg) != 0 {
+			return "", fmt.Errorf("%s", msg)
+		}
+		return "", err
+	}
+
+	return strings.TrimSuffix(stdout.String(), "\n"), nil
+}
+
+// readTarFiles returns the content of every regular file in the tar, indexed
+// by the path in the tar, and the mode of the files with a mode different to
+// the default
+func readTarFiles(r io.Reader) (map[string]string, map[string]os.FileMode, error) {
+	files := map[string]string{}
+	modes := map[string]os.FileMode{}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, nil, err
+		}
+		name, err := codeFilePath(hdr.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		files[name] = string(content)
+		if os.FileMode(hdr.Mode).Perm()&0111 != 0 {
+			modes[name] = 0755
+		}
+	}
+
+	return files, modes, nil
+}
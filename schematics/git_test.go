@@ -0,0 +1,101 @@
+package schematics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkspace_LoadGitRef(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is required to test LoadGitRef()")
+	}
+
+	dir, err := ioutil.TempDir("", "gics-git")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	run := func(args ...string) string {
+		out, err := git(dir, args...)
+		if err != nil {
+			t.Fatalf("git %v failed. %s", args, err)
+		}
+		return out
+	}
+	write := func(name, content string, mode os.FileMode) {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(content), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("init", "-q")
+	run("config", "user.email", "gics@example.com")
+	run("config", "user.name", "GICS")
+	write("README.md", "# GICS", 0644)
+	write("iac/main.tf", `variable "prefix" {}`, 0644)
+	write("iac/scripts/init.sh", "#!/bin/sh\n", 0755)
+	run("add", ".")
+	run("commit", "-q", "-m", "v1")
+	run("tag", "v1")
+	v1 := run("rev-parse", "HEAD")
+
+	write("iac/main.tf", `variable "name" {}`, 0644)
+	run("commit", "-q", "-am", "v2")
+	// local changes are not loaded
+	write("iac/main.tf", `variable "uncommitted" {}`, 0644)
+
+	w := New("git", "", nil)
+	if !assert.NoError(t, w.LoadGitRef(filepath.Join(dir, "iac"), "v1")) {
+		return
+	}
+	assert.Equal(t, map[string]string{
+		"main.tf":         `variable "prefix" {}`,
+		"scripts/init.sh": "#!/bin/sh\n",
+	}, w.tfCodeFiles)
+	assert.Equal(t, map[string]os.FileMode{"scripts/init.sh": 0755}, w.tfFileModes)
+	assert.Equal(t, v1, w.codeCommit)
+	assert.Equal(t, fmt.Sprintf("%s@%s", filepath.Join(dir, "iac"), v1), w.codeSummary())
+
+	if assert.NoError(t, w.LoadGitRef(dir, "")) {
+		assert.Equal(t, `variable "name" {}`, w.tfCodeFiles["iac/main.tf"])
+		assert.Equal(t, run("rev-parse", "HEAD"), w.codeCommit)
+	}
+
+	// uploading the code stores the commit in the workspace tags
+	w.ID = "git-9f0a1b2c-3d4e-5f"
+	var gotTags []string
+	httpmock.RegisterResponder("PATCH", "https://schematics.cloud.ibm.com/v1/workspaces/"+w.ID,
+		func(req *http.Request) (*http.Response, error) {
+			var body struct {
+				Tags []string `json:"tags"`
+			}
+			json.NewDecoder(req.Body).Decode(&body)
+			gotTags = body.Tags
+			return jsonResponder(200, fmt.Sprintf(`{"id":"%s","name":"git","tags":["%s"]}`, w.ID, strings.Join(body.Tags, `","`)))(req)
+		},
+	)
+	if assert.NoError(t, w.setCodeHash(w.codeHash())) {
+		assert.Equal(t, []string{codeHashTagPrefix + w.codeHash(), codeCommitTagPrefix + w.codeCommit}, gotTags)
+		assert.Equal(t, w.codeCommit, w.CodeCommit)
+	}
+
+	// other code source resets the commit
+	w.LoadCode(`variable "prefix" {}`)
+	assert.Empty(t, w.codeCommit)
+
+	assert.Error(t, w.LoadGitRef(dir, "unknown"))
+	assert.Error(t, w.LoadGitRef(os.TempDir(), "HEAD"))
+}
@@ -0,0 +1,215 @@
+package schematics
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// variableDecl is a `variable` block declared in the Terraform code
+type variableDecl struct {
+	Name        string
+	Type        cty.Type
+	HasDefault  bool
+	Default     string
+	Description string
+	Pos         string
+}
+
+var variableBlockSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "variable", LabelNames: []string{"name"}},
+	},
+}
+
+var variableSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "type"},
+		{Name: "default"},
+		{Name: "description"},
+	},
+}
+
+// parseCode parses the Terraform files of the code, the files that are not
+// Terraform code (*.tf or *.tf.json) are ignored
+func parseCode(files map[string]string) (map[string]*hcl.File, error) {
+	parsed, diags := parseCodeDiags(files)
+	if diags.HasErrors() {
+		return parsed, diagsError(diags)
+	}
+	return parsed, nil
+}
+
+// parseCodeDiags parses the Terraform files of the code as parseCode does,
+// returning the HCL diagnostics instead of an error
+func parseCodeDiags(files map[string]string) (map[string]*hcl.File, hcl.Diagnostics) {
+	parser := hclparse.NewParser()
+	parsed := map[string]*hcl.File{}
+	var diags hcl.Diagnostics
+
+	for _, name := range sortedKeys(files) {
+		var f *hcl.File
+		var d hcl.Diagnostics
+		switch {
+		case strings.HasSuffix(name, ".tf"):
+			f, d = parser.ParseHCL([]byte(files[name]), name)
+		case strings.HasSuffix(name, ".tf.json"):
+			f, d = parser.ParseJSON([]byte(files[name]), name)
+		default:
+			continue
+		}
+		diags = append(diags, d...)
+		if f != nil {
+			parsed[name] = f
+		}
+	}
+
+	return parsed, diags
+}
+
+// rootModule returns the directory of the root module in the code, it's the
+// workspace Folder or the top directory
+func (w *Workspace) rootModule() string {
+	return path.Clean("./" + w.Folder)
+}
+
+// moduleFiles returns the files of the module in the given directory, without
+// the files of the child modules in the subdirectories
+func moduleFiles(files map[string]string, dir string) map[string]string {
+	module := map[string]string{}
+	for name, content := range files {
+		if path.Dir(name) == dir {
+			module[name] = content
+		}
+	}
+	return module
+}
+
+// parseVariables returns the variables declared in the Terraform code
+func parseVariables(files map[string]string) ([]variableDecl, error) {
+	parsed, err := parseCode(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var decls []variableDecl
+	var diags hcl.Diagnostics
+	for _, name := range sortedFiles(parsed) {
+		content, _, d := parsed[name].Body.PartialContent(variableBlockSchema)
+		diags = append(diags, d...)
+		for _, block := range content.Blocks {
+			attrs, _, d := block.Body.PartialContent(variableSchema)
+			diags = append(diags, d...)
+
+			decl := variableDecl{
+				Name: block.Labels[0],
+				Type: cty.DynamicPseudoType,
+				Pos:  fmt.Sprintf("%s:%d", block.DefRange.Filename, block.DefRange.Start.Line),
+			}
+			if attr, ok := attrs.Attributes["type"]; ok {
+				ty, d := typeexpr.TypeConstraint(attr.Expr)
+				diags = append(diags, d...)
+				if !d.HasErrors() {
+					decl.Type = ty
+				}
+			}
+			if attr, ok := attrs.Attributes["default"]; ok {
+				decl.HasDefault = true
+				rng := attr.Expr.Range()
+				decl.Default = files[name][rng.Start.Byte:rng.End.Byte]
+			}
+			if attr, ok := attrs.Attributes["description"]; ok {
+				if v, d := attr.Expr.Value(nil); !d.HasErrors() && v.Type() == cty.String && v.IsKnown() && !v.IsNull() {
+					decl.Description = v.AsString()
+				}
+			}
+			decls = append(decls, decl)
+		}
+	}
+
+	if diags.HasErrors() {
+		return nil, diagsError(diags)
+	}
+	return decls, nil
+}
+
+// parseType parses a Terraform type constraint, i.e. `list(string)`. An empty
+// type is `any`
+func parseType(s string) (cty.Type, error) {
+	if len(strings.TrimSpace(s)) == 0 {
+		return cty.DynamicPseudoType, nil
+	}
+	expr, diags := hclsyntax.ParseExpression([]byte(s), "type", hcl.InitialPos)
+	if diags.HasErrors() {
+		return cty.NilType, diagsError(diags)
+	}
+	ty, diags := typeexpr.TypeConstraint(expr)
+	if diags.HasErrors() {
+		return cty.NilType, diagsError(diags)
+	}
+	return ty, nil
+}
+
+// typeString returns the Terraform type constraint of the given type
+func typeString(ty cty.Type) string {
+	return typeexpr.TypeString(ty)
+}
+
+// checkValue verifies the value of a variable, as it's sent to Schematics,
+// can be converted to the given type. The values of the complex types are HCL
+// expressions, i.e. `["a", "b"]` or `{ key = "value" }`
+func checkValue(value string, ty cty.Type) error {
+	if ty == cty.DynamicPseudoType || ty == cty.String {
+		return nil
+	}
+
+	expr, diags := hclsyntax.ParseExpression([]byte(value), "value", hcl.InitialPos)
+	if diags.HasErrors() {
+		return fmt.Errorf("the value is not a valid %s", typeString(ty))
+	}
+	v, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		return fmt.Errorf("the value is not a valid %s", typeString(ty))
+	}
+	if _, err := convert.Convert(v, ty); err != nil {
+		return fmt.Errorf("the value is not a valid %s, %s", typeString(ty), err)
+	}
+	return nil
+}
+
+// diagsError converts the HCL diagnostics into an error with one line per
+// error, in the format `file:line: message`
+func diagsError(diags hcl.Diagnostics) error {
+	var lines []string
+	for _, d := range diags {
+		if d.Severity != hcl.DiagError {
+			continue
+		}
+		msg := d.Summary
+		if len(d.Detail) != 0 {
+			msg += "; " + d.Detail
+		}
+		if d.Subject != nil {
+			msg = fmt.Sprintf("%s:%d: %s", d.Subject.Filename, d.Subject.Start.Line, msg)
+		}
+		lines = append(lines, msg)
+	}
+	return fmt.Errorf("%s", strings.Join(lines, "\n"))
+}
+
+func sortedFiles(files map[string]*hcl.File) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,172 @@
+package schematics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/go-sdk-core/core"
+)
+
+const (
+	defaultIAMURL = "https://iam.cloud.ibm.com/identity/token"
+	// the refresh tokens accepted by Schematics are requested with the client
+	// ID and secret of the IBM Cloud CLI
+	iamClientID     = "bx"
+	iamClientSecret = "bx"
+	iamTimeout      = 30
+)
+
+// refreshTokener is an authenticator that provides an IAM refresh token
+type refreshTokener interface {
+	RefreshToken() (string, error)
+}
+
+// iamToken is an IAM access token with its refresh token
+type iamToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	Expiration   int64  `json:"expiration"`
+
+	// refreshTime is when the tokens are renewed, before they expire
+	refreshTime time.Time
+}
+
+// iamAuthenticator authenticates the requests with an IAM access token
+// requested with the API Key, as core.IamAuthenticator does, but it keeps the
+// refresh token required by the Schematics actions. Both tokens are cached
+// and renewed before they expire
+type iamAuthenticator struct {
+	apiKey string
+	url    string
+	client *http.Client
+
+	mu    sync.Mutex
+	token *iamToken
+}
+
+func newIAMAuthenticator(apiKey string, client *http.Client) *iamAuthenticator {
+	if client == nil {
+		client = &http.Client{Timeout: iamTimeout * time.Second}
+	}
+	return &iamAuthenticator{
+		apiKey: apiKey,
+		url:    defaultIAMURL,
+		client: client,
+	}
+}
+
+// AuthenticationType implements core.Authenticator
+func (a *iamAuthenticator) AuthenticationType() string {
+	return core.AUTHTYPE_IAM
+}
+
+// Validate implements core.Authenticator
+func (a *iamAuthenticator) Validate() error {
+	if len(a.apiKey) == 0 {
+		return fmt.Errorf("the IBM Cloud API Key is required, export it in the 'IC_API_KEY' variable")
+	}
+	return nil
+}
+
+// Authenticate implements core.Authenticator, it sets the access token in the
+// Authorization header
+func (a *iamAuthenticator) Authenticate(req *http.Request) error {
+	token, err := a.getToken()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	return nil
+}
+
+// RefreshToken returns the IAM refresh token
+func (a *iamAuthenticator) RefreshToken() (string, error) {
+	token, err := a.getToken()
+	if err != nil {
+		return "", err
+	}
+	return token.RefreshToken, nil
+}
+
+// getToken returns the cached tokens, or new ones if they are about to expire
+func (a *iamAuthenticator) getToken() (*iamToken, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != nil && time.Now().Before(a.token.refreshTime) {
+		return a.token, nil
+	}
+
+	token, err := a.requestToken()
+	if err != nil {
+		return nil, err
+	}
+	a.token = token
+
+	return token, nil
+}
+
+// requestToken requests new tokens to IAM with the API Key
+func (a *iamAuthenticator) requestToken() (*iamToken, error) {
+	form := url.Values{}
+	form.Set("grant_type", "urn:ibm:params:oauth:grant-type:apikey")
+	form.Set("apikey", a.apiKey)
+
+	req, err := http.NewRequest("POST", a.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(iamClientID, iamClientSecret)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request the IAM token. %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the IAM token. %s", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to request the IAM token, %s: %s", resp.Status, body)
+	}
+
+	token := &iamToken{}
+	if err := json.Unmarshal(body, token); err != nil {
+		return nil, fmt.Errorf("failed to decode the IAM token. %s", err)
+	}
+	if len(token.AccessToken) == 0 {
+		return nil, fmt.Errorf("the IAM response has no access token")
+	}
+
+	// renew the tokens when the 80% of their time to live has passed
+	expiration := time.Unix(token.Expiration, 0)
+	token.refreshTime = expiration.Add(-time.Duration(token.ExpiresIn) * time.Second / 5)
+
+	return token, nil
+}
+
+// refreshToken returns the IAM refresh token required by the plan, apply,
+// refresh and destroy actions. It's provided by the IAM authenticator, or
+// taken from the environment variable IC_IAM_REFRESH_TOKEN for the other
+// authenticators
+func (s *Service) refreshToken() (string, error) {
+	if rt, ok := s.authenticator.(refreshTokener); ok {
+		return rt.RefreshToken()
+	}
+	if token := os.Getenv("IC_IAM_REFRESH_TOKEN"); len(token) != 0 {
+		return token, nil
+	}
+	return "", fmt.Errorf("the IAM refresh token is required by the %s authenticator, export it in the 'IC_IAM_REFRESH_TOKEN' variable or use the IBM Cloud API Key", s.authenticator.AuthenticationType())
+}
@@ -0,0 +1,101 @@
+package schematics
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/IBM/go-sdk-core/core"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIAMAuthenticator(t *testing.T) {
+	iamURL := "https://iam.test.cloud.ibm.com/identity/token"
+	calls := 0
+	httpmock.RegisterResponder("POST", iamURL, func(req *http.Request) (*http.Response, error) {
+		calls++
+		user, pass, _ := req.BasicAuth()
+		req.ParseForm()
+		if user != "bx" || pass != "bx" || req.Form.Get("apikey") != "api-key" {
+			return httpmock.NewStringResponse(400, `{"errorMessage":"invalid request"}`), nil
+		}
+		return jsonResponder(200, fmt.Sprintf(`{"access_token":"access-%d","refresh_token":"refresh-%d","token_type":"Bearer","expires_in":3600,"expiration":%d}`, calls, calls, time.Now().Add(time.Hour).Unix()))(req)
+	})
+
+	a := newIAMAuthenticator("api-key", nil)
+	a.url = iamURL
+
+	req, _ := http.NewRequest("GET", "https://schematics.cloud.ibm.com/v1/version", nil)
+	if !assert.NoError(t, a.Authenticate(req)) {
+		return
+	}
+	assert.Equal(t, "Bearer access-1", req.Header.Get("Authorization"))
+
+	token, err := a.RefreshToken()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "refresh-1", token)
+	assert.Equal(t, 1, calls, "the tokens should be cached")
+
+	// the tokens are renewed when they are about to expire
+	a.token.refreshTime = time.Now().Add(-time.Second)
+	token, err = a.RefreshToken()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "refresh-2", token)
+	assert.Equal(t, 2, calls)
+
+	a = newIAMAuthenticator("wrong-key", nil)
+	a.url = iamURL
+	_, err = a.RefreshToken()
+	assert.EqualError(t, err, `failed to request the IAM token, 400: {"errorMessage":"invalid request"}`)
+}
+
+func TestService_refreshToken(t *testing.T) {
+	s := NewService(&ServiceOptions{BaseURL: defaultAPIEndpoint, Authenticator: &core.NoAuthAuthenticator{}})
+
+	os.Unsetenv("IC_IAM_REFRESH_TOKEN")
+	_, err := s.refreshToken()
+	assert.EqualError(t, err, "the IAM refresh token is required by the noAuth authenticator, export it in the 'IC_IAM_REFRESH_TOKEN' variable or use the IBM Cloud API Key")
+
+	os.Setenv("IC_IAM_REFRESH_TOKEN", "env-refresh-token")
+	defer os.Unsetenv("IC_IAM_REFRESH_TOKEN")
+	token, err := s.refreshToken()
+	assert.NoError(t, err)
+	assert.Equal(t, "env-refresh-token", token)
+}
+
+func TestWorkspace_Refresh(t *testing.T) {
+	workspaceName := "refresh"
+	workspaceID := fmt.Sprintf("%s-7c1de2f3-4a5b-6c", workspaceName)
+	iamURL := "https://iam.refresh.test.cloud.ibm.com/identity/token"
+
+	httpmock.RegisterResponder("POST", iamURL,
+		jsonResponder(200, fmt.Sprintf(`{"access_token":"access-token","refresh_token":"refresh-token","token_type":"Bearer","expires_in":3600,"expiration":%d}`, time.Now().Add(time.Hour).Unix())))
+
+	httpmock.RegisterResponder("GET", "https://schematics.cloud.ibm.com/v1/workspaces/"+workspaceID,
+		jsonResponder(200, fmt.Sprintf(`{"id":"%s","name":"%s","status":"ACTIVE","workspace_status":{"frozen":false,"locked":false}}`, workspaceID, workspaceName)))
+	var refreshToken string
+	httpmock.RegisterResponder("PUT", fmt.Sprintf("https://schematics.cloud.ibm.com/v1/workspaces/%s/refresh", workspaceID),
+		func(req *http.Request) (*http.Response, error) {
+			refreshToken = req.Header.Get("refresh_token")
+			return jsonResponder(202, `{}`)(req)
+		})
+
+	service := NewService(&ServiceOptions{BaseURL: defaultAPIEndpoint, APIKey: "api-key"})
+	service.authenticator.(*iamAuthenticator).url = iamURL
+	w := New(workspaceName, "", service)
+	w.ID = workspaceID
+
+	act, err := w.Refresh()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, &NilActivity, act)
+	assert.Equal(t, "refresh-token", refreshToken, "the IAM refresh token should be sent")
+}
@@ -0,0 +1,189 @@
+package schematics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	apiv1 "github.com/johandry/gics/schematics/api/v1"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const (
+	getWorkspaceInputMetadataTimeout = 50
+)
+
+// InputIssueKind is the kind of problem found in the workspace variables
+type InputIssueKind string
+
+const (
+	// InputIssueMissing is a required variable without value
+	InputIssueMissing = InputIssueKind("missing")
+
+	// InputIssueUnknown is a variable not declared in the Terraform code
+	InputIssueUnknown = InputIssueKind("unknown")
+
+	// InputIssueType is a variable with a value that doesn't match its type
+	InputIssueType = InputIssueKind("type")
+)
+
+// InputIssue is a problem found in the workspace variables by ValidateInputs()
+type InputIssue struct {
+	Kind     InputIssueKind `json:"kind" yaml:"kind"`
+	Variable string         `json:"variable" yaml:"variable"`
+	Message  string         `json:"message" yaml:"message"`
+}
+
+func (i InputIssue) String() string {
+	return fmt.Sprintf("%s variable %q: %s", i.Kind, i.Variable, i.Message)
+}
+
+// ValidateInputs verifies the workspace variables against the variables
+// declared in the Terraform code. It reports the required variables without
+// value, the variables not declared and the values that don't match the
+// declared type. The declarations are parsed from the loaded code, or taken
+// from the input metadata of the existing workspace when the code is in a Git
+// repository. Run() and Reconcile() verify the variables of the loaded code
+// before any action runs
+func (w *Workspace) ValidateInputs(ctx context.Context) ([]InputIssue, error) {
+	decls, err := w.declaredVariables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	declared := map[string]variableDecl{}
+	for _, d := range decls {
+		declared[d.Name] = d
+	}
+	values := map[string]Variable{}
+	for _, v := range w.Variables {
+		values[v.Name] = v
+	}
+
+	issues := []InputIssue{}
+	for _, d := range decls {
+		v, ok := values[d.Name]
+		if !ok {
+			if !d.HasDefault {
+				issues = append(issues, InputIssue{InputIssueMissing, d.Name, "the variable is required, it has no default value"})
+			}
+			continue
+		}
+		// The API doesn't return the value of the secure variables
+		if v.Secure && len(v.Value) == 0 {
+			continue
+		}
+		if err := checkValue(v.Value, d.Type); err != nil {
+			issues = append(issues, InputIssue{InputIssueType, d.Name, err.Error()})
+		}
+	}
+	for _, v := range w.Variables {
+		if _, ok := declared[v.Name]; !ok {
+			issues = append(issues, InputIssue{InputIssueUnknown, v.Name, "the variable is not declared in the Terraform code"})
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Variable < issues[j].Variable })
+
+	return issues, nil
+}
+
+// checkInputs fails if a required variable has no value or a value doesn't
+// match its type, the variables not declared are logged. The variables are
+// verified only if the code is loaded, the code in a Git repository is not
+// available before the workspace is created
+func (w *Workspace) checkInputs() error {
+	if len(w.tfCodeFiles) == 0 {
+		return nil
+	}
+	issues, err := w.ValidateInputs(context.Background())
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	for _, issue := range issues {
+		if issue.Kind == InputIssueUnknown {
+			w.logPrintf("%s", issue)
+			continue
+		}
+		errs = append(errs, issue.String())
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("the variables of the workspace %q are not valid:\n%s", w.Name, strings.Join(errs, "\n"))
+}
+
+// declaredVariables returns the variables declared in the root module of the
+// loaded code, or in the code of the existing workspace if there is no code
+// loaded. The variables of the child modules are not workspace inputs
+func (w *Workspace) declaredVariables(ctx context.Context) ([]variableDecl, error) {
+	if len(w.tfCodeFiles) != 0 {
+		return parseVariables(moduleFiles(w.tfCodeFiles, w.rootModule()))
+	}
+	return w.inputMetadata(ctx)
+}
+
+// inputMetadata returns the variables declared in the Terraform code of the
+// existing workspace
+func (w *Workspace) inputMetadata(ctx context.Context) ([]variableDecl, error) {
+	// InputMetadata Timeout
+	ctx, cancelFunc := context.WithTimeout(ctx, getWorkspaceInputMetadataTimeout*time.Second)
+	defer cancelFunc()
+
+	wID, tID := w.ID, w.templateID
+	if len(wID) == 0 || len(tID) == 0 {
+		existing, err := w.api().lookup(ctx, w.ID, w.Name, w.ResourceGroup)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			return nil, fmt.Errorf("the workspace %q has no code loaded and doesn't exist, cannot validate the variables", w.Name)
+		}
+		wID, tID = existing.ID, existing.templateID
+	}
+
+	params := &apiv1.GetWorkspaceInputMetadataParams{}
+	resp, err := w.api().clientWithResponses.GetWorkspaceInputMetadataWithResponse(ctx, wID, tID, params)
+	if err != nil {
+		return nil, err
+	}
+	if code := resp.StatusCode(); code != 200 {
+		return nil, getAPIError("failed to get the workspace input metadata", resp.Body)
+	}
+
+	decls := []variableDecl{}
+	if resp.JSON200 == nil {
+		return decls, nil
+	}
+	for _, m := range *resp.JSON200 {
+		name, _ := m["name"].(string)
+		if len(name) == 0 {
+			continue
+		}
+		decl := variableDecl{
+			Name: name,
+			Type: cty.DynamicPseudoType,
+			Pos:  "values_metadata",
+		}
+		if t, ok := m["type"].(string); ok {
+			// Unknown types are not validated
+			if ty, err := parseType(t); err == nil {
+				decl.Type = ty
+			}
+		}
+		if def, ok := m["default"]; ok {
+			decl.HasDefault = true
+			decl.Default = fmt.Sprintf("%v", def)
+		}
+		if required, ok := m["required"].(bool); ok && !required {
+			decl.HasDefault = true
+		}
+		decl.Description, _ = m["description"].(string)
+		decls = append(decls, decl)
+	}
+
+	return decls, nil
+}
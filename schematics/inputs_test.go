@@ -0,0 +1,182 @@
+package schematics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkspace_ValidateInputs(t *testing.T) {
+	code := `
+variable "prefix" {}
+variable "region" {
+  default = "us-south"
+}
+variable "count_vsi" {
+  type = number
+}
+variable "zones" {
+  type    = list(string)
+  default = []
+}
+variable "tags" {
+  type = map(string)
+  validation {
+    condition     = length(var.tags) > 0
+    error_message = "At least one tag is required."
+  }
+}
+`
+	tests := []struct {
+		name      string
+		variables map[string]string
+		want      []InputIssue
+	}{
+		{"valid", map[string]string{"prefix": "gics", "count_vsi": "2", "zones": `["us-south-1", "us-south-2"]`, "tags": `{ env = "dev" }`}, []InputIssue{}},
+		{"missing", map[string]string{"count_vsi": "2"}, []InputIssue{
+			{InputIssueMissing, "prefix", "the variable is required, it has no default value"},
+			{InputIssueMissing, "tags", "the variable is required, it has no default value"},
+		}},
+		{"unknown", map[string]string{"prefix": "gics", "count_vsi": "2", "tags": "{}", "name": "gics"}, []InputIssue{
+			{InputIssueUnknown, "name", "the variable is not declared in the Terraform code"},
+		}},
+		{"type", map[string]string{"prefix": "gics", "count_vsi": "two", "zones": `"us-south-1"`, "tags": "{}"}, []InputIssue{
+			{InputIssueType, "count_vsi", "the value is not a valid number"},
+			{InputIssueType, "zones", "the value is not a valid list(string), list of string required"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := New("validate", "", nil)
+			if err := w.LoadCode(code); !assert.NoError(t, err) {
+				return
+			}
+			for _, name := range sortedKeys(tt.variables) {
+				w.AddVar(name, tt.variables[name], "", "", false)
+			}
+
+			got, err := w.ValidateInputs(context.Background())
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+
+	w := New("validate", "", nil)
+	w.LoadCode(`variable "prefix" {`)
+	_, err := w.ValidateInputs(context.Background())
+	assert.Error(t, err, "ValidateInputs() should fail if the code is not valid")
+}
+
+func TestWorkspace_ValidateInputs_metadata(t *testing.T) {
+	workspaceName := "metadata"
+	workspaceID := fmt.Sprintf("%s-6c7d8e9f-0a1b-2c", workspaceName)
+	templateID := "iac-f6a7b8c9-0d1e-2f"
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://schematics.cloud.ibm.com/v1/workspaces/%s/template_data/%s/values_metadata", workspaceID, templateID),
+		func(req *http.Request) (*http.Response, error) {
+			// Get the fixture with the following code after getting the Token:
+			// export TOKEN=$(cat .token | jq -r .access_token)
+			// export WID=
+			// export TID=
+			// curl -X GET "https://schematics.cloud.ibm.com/v1/workspaces/$WID/template_data/$TID/values_metadata" -H "Authorization: Bearer $TOKEN" -H 'Accept: application/json'
+			fixture := `[{"name":"project_name","type":"string","description":"the project name"},{"name":"environment","type":"string","default":"dev"},{"name":"public_key","type":"string"},{"name":"port","type":"number","default":"22"},{"name":"admin_port","type":"number"}]`
+			resp := httpmock.NewStringResponse(200, fixture)
+			resp.Header.Add("Content-Type", "application/json; charset=utf-8")
+			return resp, nil
+		},
+	)
+
+	w := New(workspaceName, "", nil)
+	w.ID = workspaceID
+	w.templateID = templateID
+	w.AddVar("project_name", "gics", "", "", false)
+	w.AddVar("port", "ssh", "", "", false)
+	// the API doesn't return the value of the secure variables
+	w.AddVar("admin_port", "", "", "", true)
+
+	got, err := w.ValidateInputs(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	want := []InputIssue{
+		{InputIssueType, "port", "the value is not a valid number"},
+		{InputIssueMissing, "public_key", "the variable is required, it has no default value"},
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestWorkspace_checkInputs(t *testing.T) {
+	w := New("check", "", nil)
+	if err := w.LoadCode(`variable "prefix" {}
+variable "count_vsi" {
+  type = number
+}
+`); !assert.NoError(t, err) {
+		return
+	}
+	w.AddVar("count_vsi", "two", "", "", false)
+	w.AddVar("name", "gics", "", "", false)
+
+	err := w.checkInputs()
+	assert.EqualError(t, err, `the variables of the workspace "check" are not valid:
+type variable "count_vsi": the value is not a valid number
+missing variable "prefix": the variable is required, it has no default value`)
+
+	_, err = w.Reconcile()
+	assert.Error(t, err, "Reconcile() should fail before any request with invalid variables")
+
+	// the variables not declared are only logged
+	w = New("check", "", nil)
+	w.LoadCode(`variable "prefix" {}`)
+	w.AddVar("prefix", "gics", "", "", false)
+	w.AddVar("name", "gics", "", "", false)
+	assert.NoError(t, w.checkInputs())
+
+	// without code loaded there is nothing to verify
+	assert.NoError(t, New("check", "", nil).checkInputs())
+}
+
+func TestWorkspace_ValidateInputs_modules(t *testing.T) {
+	files := map[string][]byte{
+		"main.tf": []byte(`variable "prefix" {}
+module "net" {
+  source = "./modules/net"
+  name   = var.prefix
+}
+`),
+		"modules/net/variables.tf":           []byte(`variable "name" {}`),
+		"vendor/modules/subnet/variables.tf": []byte(`variable "cidr" {}`),
+	}
+
+	// the required inputs of the child modules are not workspace variables
+	w := New("modules", "", nil)
+	if err := w.LoadFiles(files); !assert.NoError(t, err) {
+		return
+	}
+	w.AddVar("prefix", "gics", "", "", false)
+	got, err := w.ValidateInputs(context.Background())
+	if assert.NoError(t, err) {
+		assert.Equal(t, []InputIssue{}, got)
+	}
+	assert.NoError(t, w.checkInputs())
+
+	// the root module is in the workspace folder
+	w = New("modules", "", nil)
+	w.Folder = "modules/net"
+	if err := w.LoadFiles(files); !assert.NoError(t, err) {
+		return
+	}
+	w.AddVar("prefix", "gics", "", "", false)
+	got, err = w.ValidateInputs(context.Background())
+	if assert.NoError(t, err) {
+		assert.Equal(t, []InputIssue{
+			{InputIssueMissing, "name", "the variable is required, it has no default value"},
+			{InputIssueUnknown, "prefix", "the variable is not declared in the Terraform code"},
+		}, got)
+	}
+}
@@ -0,0 +1,142 @@
+package schematics
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// LintSeverity is the severity of a problem found in the code by Lint()
+type LintSeverity string
+
+const (
+	// LintError is a problem that makes the Terraform plan fail
+	LintError = LintSeverity("error")
+
+	// LintWarning is a problem reported by Terraform that doesn't make the plan
+	// fail
+	LintWarning = LintSeverity("warning")
+)
+
+// LintIssue is a problem found in the code by Lint(). The File and Line are
+// empty for the problems found in the workspace variables
+type LintIssue struct {
+	Severity LintSeverity `json:"severity" yaml:"severity"`
+	File     string       `json:"file,omitempty" yaml:"file,omitempty"`
+	Line     int          `json:"line,omitempty" yaml:"line,omitempty"`
+	Message  string       `json:"message" yaml:"message"`
+}
+
+func (i LintIssue) String() string {
+	if len(i.File) == 0 {
+		return fmt.Sprintf("%s: %s", i.Severity, i.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s: %s", i.File, i.Line, i.Severity, i.Message)
+}
+
+// Lint parses the Terraform files of the loaded code and reports the syntax
+// errors, the references to variables not declared in the module and the
+// workspace variables not declared in the root module, so they are found
+// before the code is uploaded and planned. The issues are sorted by file and
+// line, the workspace variables are reported last
+func (w *Workspace) Lint() []LintIssue {
+	issues := []LintIssue{}
+	if len(w.tfCodeFiles) == 0 {
+		return issues
+	}
+
+	parsed, diags := parseCodeDiags(w.tfCodeFiles)
+	for _, d := range diags {
+		if d.Severity != hcl.DiagError {
+			continue
+		}
+		msg := d.Summary
+		if len(d.Detail) != 0 {
+			msg += "; " + d.Detail
+		}
+		issue := LintIssue{Severity: LintError, Message: msg}
+		if d.Subject != nil {
+			issue.File, issue.Line = d.Subject.Filename, d.Subject.Start.Line
+		}
+		issues = append(issues, issue)
+	}
+
+	// the variables are declared per module, a module is a directory
+	declared := map[string]map[string]bool{}
+	for _, name := range sortedFiles(parsed) {
+		dir := path.Dir(name)
+		if declared[dir] == nil {
+			declared[dir] = map[string]bool{}
+		}
+		content, _, _ := parsed[name].Body.PartialContent(variableBlockSchema)
+		for _, block := range content.Blocks {
+			declared[dir][block.Labels[0]] = true
+		}
+	}
+
+	for _, name := range sortedFiles(parsed) {
+		body, ok := parsed[name].Body.(*hclsyntax.Body)
+		if !ok {
+			// the references are not verified in the JSON files
+			continue
+		}
+		vars := declared[path.Dir(name)]
+		hclsyntax.VisitAll(body, func(node hclsyntax.Node) hcl.Diagnostics {
+			expr, ok := node.(*hclsyntax.ScopeTraversalExpr)
+			if !ok || expr.Traversal.RootName() != "var" || len(expr.Traversal) < 2 {
+				return nil
+			}
+			attr, ok := expr.Traversal[1].(hcl.TraverseAttr)
+			if !ok || vars[attr.Name] {
+				return nil
+			}
+			rng := expr.SrcRange
+			issues = append(issues, LintIssue{
+				Severity: LintError,
+				File:     rng.Filename,
+				Line:     rng.Start.Line,
+				Message:  fmt.Sprintf("reference to undeclared input variable %q", attr.Name),
+			})
+			return nil
+		})
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		return issues[i].Line < issues[j].Line
+	})
+
+	for _, v := range w.Variables {
+		if !declared[w.rootModule()][v.Name] {
+			issues = append(issues, LintIssue{
+				Severity: LintWarning,
+				Message:  fmt.Sprintf("the variable %q is set but it's not declared in the root module", v.Name),
+			})
+		}
+	}
+
+	return issues
+}
+
+// lint lints the loaded code before it's uploaded, the warnings are logged
+// and the errors are returned
+func (w *Workspace) lint() error {
+	var errs []string
+	for _, issue := range w.Lint() {
+		if issue.Severity != LintError {
+			w.logPrintf("%s", issue)
+			continue
+		}
+		errs = append(errs, issue.String())
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("the code of the workspace %q has errors:\n%s", w.Name, strings.Join(errs, "\n"))
+}
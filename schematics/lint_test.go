@@ -0,0 +1,81 @@
+package schematics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkspace_Lint(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		variables []string
+		want      []LintIssue
+	}{
+		{"valid", map[string]string{
+			"main.tf":                 "variable \"prefix\" {}\nmodule \"network\" {\n  source = \"./modules/network\"\n  name   = var.prefix\n}\n",
+			"modules/network/main.tf": "variable \"name\" {}\noutput \"name\" { value = \"${var.name}-net\" }\n",
+		}, []string{"prefix"}, []LintIssue{}},
+		{"syntax", map[string]string{
+			"main.tf": "variable \"prefix\" {\n",
+		}, nil, []LintIssue{
+			{LintError, "main.tf", 2, "Argument or block definition required; An argument or block definition is required here."},
+		}},
+		{"undeclared", map[string]string{
+			"main.tf":                 "variable \"prefix\" {}\nmodule \"network\" {\n  source = \"./modules/network\"\n  name   = var.prefix\n}\n",
+			"outputs.tf":              "output \"name\" {\n  value = var.name\n}\n",
+			"modules/network/main.tf": "output \"name\" { value = \"${var.prefix}-net\" }\n",
+		}, []string{"prefix", "region"}, []LintIssue{
+			{LintError, "modules/network/main.tf", 1, `reference to undeclared input variable "prefix"`},
+			{LintError, "outputs.tf", 2, `reference to undeclared input variable "name"`},
+			{LintWarning, "", 0, `the variable "region" is set but it's not declared in the root module`},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := New("lint", "", nil)
+			files := map[string][]byte{}
+			for name, content := range tt.files {
+				files[name] = []byte(content)
+			}
+			if err := w.LoadFiles(files); !assert.NoError(t, err) {
+				return
+			}
+			for _, name := range tt.variables {
+				w.AddVar(name, "value", "", "", false)
+			}
+			assert.Equal(t, tt.want, w.Lint())
+		})
+	}
+}
+
+func TestWorkspace_Lint_folder(t *testing.T) {
+	w := New("lint", "", nil)
+	w.Folder = "terraform"
+	err := w.LoadFiles(map[string][]byte{
+		"terraform/main.tf": []byte("variable \"prefix\" {}\noutput \"name\" { value = var.prefix }\n"),
+		"README.md":         []byte("# Lint\n"),
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	w.AddVar("prefix", "value", "", "", false)
+	w.AddVar("prefx", "value", "", "", false)
+
+	assert.Equal(t, []LintIssue{
+		{LintWarning, "", 0, `the variable "prefx" is set but it's not declared in the root module`},
+	}, w.Lint())
+}
+
+func TestWorkspace_UploadTar_lint(t *testing.T) {
+	w := New("lint", "", nil)
+	w.ID, w.templateID = "lint-id", "lint-template"
+	if err := w.LoadCode("output \"name\" {\n  value = var.name\n}\n"); !assert.NoError(t, err) {
+		return
+	}
+	err := w.UploadTar(w.tfBuf)
+	if assert.Error(t, err) {
+		assert.Equal(t, "the code of the workspace \"lint\" has errors:\nmain.tf:2: error: reference to undeclared input variable \"name\"", err.Error())
+	}
+}
@@ -2,6 +2,7 @@ package schematics
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	apiv1 "github.com/johandry/gics/schematics/api/v1"
@@ -78,3 +79,55 @@ func (s *Service) List(ctx context.Context) (*WorkspaceList, error) {
 		Workspaces: workspaces,
 	}, nil
 }
+
+// Get returns the existing Schematics workspace with the given ID using the
+// default Schematics service
+func Get(id string) (*Workspace, error) {
+	ctx := context.Background()
+	return defaultService.Get(ctx, id)
+}
+
+// Get returns the existing Schematics workspace with the given ID
+func (s *Service) Get(ctx context.Context, id string) (*Workspace, error) {
+	// Get Timeout
+	ctx, cancelFunc := context.WithTimeout(ctx, listTimeout*time.Second)
+	defer cancelFunc()
+
+	resp, err := s.regional(regionOf(id)).clientWithResponses.GetWorkspaceWithResponse(ctx, id, &apiv1.GetWorkspaceParams{})
+	if err != nil {
+		return nil, err
+	}
+
+	if code := resp.StatusCode(); code != 200 {
+		return nil, getAPIError("failed to get the workspace", resp.Body)
+	}
+
+	w := &Workspace{
+		service: s,
+	}
+	w.update(resp.JSON200)
+
+	return w, nil
+}
+
+// Find returns the existing Schematics workspace with the given name, and
+// resource group if it's not empty, using the default Schematics service
+func Find(name, resourceGroup string) (*Workspace, error) {
+	ctx := context.Background()
+	return defaultService.Find(ctx, name, resourceGroup)
+}
+
+// Find returns the existing Schematics workspace with the given name, and
+// resource group if it's not empty. It fails if the workspace is not found or
+// there is more than one workspace with that name
+func (s *Service) Find(ctx context.Context, name, resourceGroup string) (*Workspace, error) {
+	w, err := s.lookup(ctx, "", name, resourceGroup)
+	if err != nil {
+		return nil, err
+	}
+	if w == nil {
+		return nil, fmt.Errorf("not found the workspace %q", name)
+	}
+
+	return w, nil
+}
@@ -21,9 +21,27 @@ func TestMain(m *testing.M) {
 	httpmock.RegisterResponder("POST", "https://iam.cloud.ibm.com/identity/token",
 		httpmock.NewStringResponder(200, fixture))
 
+	// Get the fixture with the following code after getting the Token:
+	// export TOKEN=$(cat .token | jq -r .access_token)
+	// curl -s -X GET "https://schematics.cloud.ibm.com/v1/locations" -H "Authorization: Bearer $TOKEN" -H 'Accept: application/json'
+	httpmock.RegisterResponder("GET", "https://schematics.cloud.ibm.com/v1/locations",
+		jsonResponder(200, `[{"id":"us-south","name":"Dallas","kind":"region","geography":"North America","country":"United States","metro":"Dallas"},{"id":"us-east","name":"Washington DC","kind":"region","geography":"North America","country":"United States","metro":"Washington DC"},{"id":"eu-gb","name":"London","kind":"region","geography":"Europe","country":"United Kingdom","metro":"London"},{"id":"eu-de","name":"Frankfurt","kind":"region","geography":"Europe","country":"Germany","metro":"Frankfurt"}]`))
+
+	// the requests of the tests are not rate limited
+	defaultService.limiter.read, defaultService.limiter.write = nil, nil
+
 	os.Exit(m.Run())
 }
 
+// jsonResponder returns a responder with the given status code and JSON body
+func jsonResponder(code int, body string) httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(code, body)
+		resp.Header.Add("Content-Type", "application/json; charset=utf-8")
+		return resp, nil
+	}
+}
+
 func TestList(t *testing.T) {
 	httpmock.RegisterResponder("GET", "https://schematics.cloud.ibm.com/v1/workspaces",
 		func(req *http.Request) (*http.Response, error) {
@@ -0,0 +1,209 @@
+package schematics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest is the declarative definition of a Schematics workspace, it's the
+// content of a YAML or JSON file to keep the workspace as code. Example:
+//
+//	name: GICS-Demo
+//	description: Resource Group for the GICS demo
+//	location: us-south
+//	resource_group: Default
+//	tags: [gics, demo]
+//	type: terraform_v0.13
+//	code: ./terraform        # local file or directory, relative to the manifest
+//	exclude: [.terraform/]   # files in the code that are not uploaded
+//	variables:
+//	  - name: prefix
+//	    value: gics-demo
+//	env_values:
+//	  - TF_LOG: DEBUG
+//
+// Use `git_repo` instead of `code` to get the Terraform code from a Git repository
+type Manifest struct {
+	ID            string         `json:"id,omitempty" yaml:"id,omitempty"`
+	Name          string         `json:"name" yaml:"name"`
+	Description   string         `json:"description,omitempty" yaml:"description,omitempty"`
+	Location      string         `json:"location,omitempty" yaml:"location,omitempty"`
+	ResourceGroup string         `json:"resource_group,omitempty" yaml:"resource_group,omitempty"`
+	Tags          []string       `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Type          string         `json:"type,omitempty" yaml:"type,omitempty"`
+	Folder        string         `json:"folder,omitempty" yaml:"folder,omitempty"`
+	GitRepo       *GitRepo       `json:"git_repo,omitempty" yaml:"git_repo,omitempty"`
+	Code          string         `json:"code,omitempty" yaml:"code,omitempty"`
+	Exclude       []string       `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+	SecretScan    SecretScanMode `json:"secret_scan,omitempty" yaml:"secret_scan,omitempty"`
+	Variables     []Variable     `json:"variables,omitempty" yaml:"variables,omitempty"`
+	EnvValues     []EnvVariable  `json:"env_values,omitempty" yaml:"env_values,omitempty"`
+}
+
+// LoadManifest reads the YAML or JSON manifest file and returns the Workspace
+// defined in it, using the default Schematics service
+func LoadManifest(path string) (*Workspace, error) {
+	return defaultService.LoadManifest(path)
+}
+
+// LoadManifest reads the YAML or JSON manifest file and returns the Workspace
+// defined in it. The local code, if any, is loaded into the workspace
+func (s *Service) LoadManifest(path string) (*Workspace, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{}
+	if isJSONFile(path) {
+		err = json.Unmarshal(data, m)
+	} else {
+		err = yaml.Unmarshal(data, m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the manifest %q. %s", path, err)
+	}
+
+	return m.workspace(s, filepath.Dir(path))
+}
+
+// SaveManifest writes the workspace definition to the given path. The file
+// format is JSON if the file extension is `.json`, otherwise it's YAML. The
+// values of the secure variables are not saved, see Manifest()
+func (w *Workspace) SaveManifest(path string) error {
+	m := w.Manifest()
+
+	if len(w.codePath) != 0 {
+		baseDir, err := filepath.Abs(filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		if code, err := filepath.Rel(baseDir, w.codePath); err == nil {
+			m.Code = filepath.ToSlash(code)
+		}
+	}
+
+	var data []byte
+	var err error
+	if isJSONFile(path) {
+		data, err = json.MarshalIndent(m, "", "  ")
+	} else {
+		data, err = yaml.Marshal(m)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode the manifest. %s", err)
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// Manifest returns the declarative definition of the workspace. The Code field
+// is the absolute path of the local code, if it was loaded from a file or directory.
+// The value of the secure variables is blank, so the manifest can be saved
+// without secrets, set them with SetVar() from the environment or a secrets manager
+func (w *Workspace) Manifest() *Manifest {
+	var variables []Variable
+	if w.Variables != nil {
+		variables = make([]Variable, len(w.Variables))
+		for i, v := range w.Variables {
+			if v.Secure {
+				v.Value = ""
+			}
+			variables[i] = v
+		}
+	}
+
+	return &Manifest{
+		ID:            w.ID,
+		Name:          w.Name,
+		Description:   w.Description,
+		Location:      w.Location,
+		ResourceGroup: w.ResourceGroup,
+		Tags:          w.Tags,
+		Type:          w.Type,
+		Folder:        w.Folder,
+		GitRepo:       w.GitRepo,
+		Code:          w.codePath,
+		Exclude:       w.excludes,
+		SecretScan:    w.SecretScan,
+		Variables:     variables,
+		EnvValues:     w.EnvValues,
+	}
+}
+
+// workspace creates the Workspace defined by the manifest. The local code path
+// is relative to baseDir, the directory of the manifest file
+func (m *Manifest) workspace(service *Service, baseDir string) (*Workspace, error) {
+	if len(m.Name) == 0 {
+		return nil, fmt.Errorf("the workspace name is required in the manifest")
+	}
+	if len(m.Code) != 0 && m.GitRepo != nil {
+		return nil, fmt.Errorf("the workspace code can be in a Git repository or local, but not both")
+	}
+
+	switch m.SecretScan {
+	case "", SecretScanBlock, SecretScanWarn, SecretScanOff:
+	default:
+		return nil, fmt.Errorf("unknown secret scan mode %q, it can be %q, %q or %q", m.SecretScan, SecretScanBlock, SecretScanWarn, SecretScanOff)
+	}
+
+	w := New(m.Name, m.Description, service)
+	w.ID = m.ID
+	w.Location = m.Location
+	w.ResourceGroup = m.ResourceGroup
+	w.Tags = m.Tags
+	w.Folder = m.Folder
+	w.GitRepo = m.GitRepo
+	w.EnvValues = m.EnvValues
+	w.SecretScan = m.SecretScan
+	// Without type the existing workspace keeps its type, and a new one is
+	// created with the default type
+	w.Type = m.Type
+
+	for _, v := range m.Variables {
+		if err := w.AddVar(v.Name, v.Value, v.Type, v.Description, v.Secure); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Exclude(m.Exclude...); err != nil {
+		return nil, err
+	}
+
+	if len(m.Code) == 0 {
+		return w, nil
+	}
+
+	codePath := m.Code
+	if !filepath.IsAbs(codePath) {
+		codePath = filepath.Join(baseDir, codePath)
+	}
+	info, err := os.Stat(codePath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return w, w.LoadDir(codePath)
+	}
+
+	code, err := ioutil.ReadFile(codePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.LoadCode(string(code)); err != nil {
+		return nil, err
+	}
+	w.codePath, _ = filepath.Abs(codePath)
+
+	return w, nil
+}
+
+func isJSONFile(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) == ".json"
+}
@@ -0,0 +1,144 @@
+package schematics
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gics-manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	codeDir := filepath.Join(dir, "terraform")
+	if err := os.MkdirAll(filepath.Join(codeDir, "modules", "rg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	mainTF := `variable "prefix" {}`
+	moduleTF := `resource "ibm_resource_group" "group" {}`
+	ioutil.WriteFile(filepath.Join(codeDir, "main.tf"), []byte(mainTF), 0644)
+	ioutil.WriteFile(filepath.Join(codeDir, "modules", "rg", "main.tf"), []byte(moduleTF), 0644)
+
+	manifest := `
+name: GICS-Demo
+description: Resource Group for the GICS demo
+location: us-south
+resource_group: Default
+tags: [gics, demo]
+code: ./terraform
+variables:
+  - name: prefix
+    value: gics-demo
+  - name: enable
+    value: "true"
+    type: bool
+env_values:
+  - TF_LOG: DEBUG
+`
+	manifestPath := filepath.Join(dir, "workspace.yaml")
+	if err := ioutil.WriteFile(manifestPath, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := LoadManifest(manifestPath)
+	if !assert.NoError(t, err, "LoadManifest() should not fail") {
+		return
+	}
+
+	assert.Equal(t, "GICS-Demo", w.Name)
+	assert.Equal(t, "Resource Group for the GICS demo", w.Description)
+	assert.Equal(t, "us-south", w.Location)
+	assert.Equal(t, "Default", w.ResourceGroup)
+	assert.Equal(t, []string{"gics", "demo"}, w.Tags)
+	assert.Empty(t, w.Type, "the type should be empty when it's not in the manifest")
+	assert.Equal(t, []Variable{
+		{"prefix", "gics-demo", "string", "", false},
+		{"enable", "true", "bool", "", false},
+	}, w.Variables)
+	assert.Equal(t, []EnvVariable{{"TF_LOG": "DEBUG"}}, w.EnvValues)
+	assert.Equal(t, map[string]string{
+		"main.tf":            mainTF,
+		"modules/rg/main.tf": moduleTF,
+	}, w.tfCodeFiles)
+	assert.NotNil(t, w.tfBuf, "the code should be loaded")
+
+	// Round-trip: save it as JSON in a different directory and load it again
+	outDir := filepath.Join(dir, "out")
+	os.Mkdir(outDir, 0755)
+	jsonPath := filepath.Join(outDir, "workspace.json")
+	if !assert.NoError(t, w.SaveManifest(jsonPath), "SaveManifest() should not fail") {
+		return
+	}
+
+	got, err := LoadManifest(jsonPath)
+	if !assert.NoError(t, err, "LoadManifest() should not fail loading the saved manifest") {
+		return
+	}
+	assert.Equal(t, w.Manifest(), got.Manifest())
+	assert.Equal(t, w.tfCodeFiles, got.tfCodeFiles)
+
+	saved, _ := ioutil.ReadFile(jsonPath)
+	assert.Contains(t, string(saved), `"code": "../terraform"`)
+}
+
+func TestWorkspace_SaveManifest_secure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gics-manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	w := New("GICS-Demo", "", nil)
+	w.AddVar("prefix", "gics-demo", "", "", false)
+	w.AddVar("api_key", "some-secret", "", "IBM Cloud API key", true)
+
+	path := filepath.Join(dir, "workspace.yml")
+	if !assert.NoError(t, w.SaveManifest(path), "SaveManifest() should not fail") {
+		return
+	}
+	assert.Equal(t, "some-secret", w.Variables[1].Value, "SaveManifest() should not modify the workspace variables")
+
+	saved, _ := ioutil.ReadFile(path)
+	assert.NotContains(t, string(saved), "some-secret")
+
+	got, err := LoadManifest(path)
+	if !assert.NoError(t, err, "LoadManifest() should not fail loading the saved manifest") {
+		return
+	}
+	assert.Equal(t, []Variable{
+		{"prefix", "gics-demo", "string", "", false},
+		{"api_key", "", "string", "IBM Cloud API key", true},
+	}, got.Variables)
+}
+
+func TestLoadManifest_errors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gics-manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name     string
+		manifest string
+	}{
+		{"no name", "description: no name"},
+		{"code and repo", "name: ws\ncode: main.tf\ngit_repo:\n  url: https://github.com/johandry/gics-pub-test"},
+		{"missing code", "name: ws\ncode: not_found.tf"},
+		{"invalid yaml", "name: [ws"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "workspace.yml")
+			ioutil.WriteFile(path, []byte(tt.manifest), 0644)
+			_, err := LoadManifest(path)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package schematics
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// vendorModulesDir is the directory in the uploaded code with the local modules
+// outside of the code directory
+const vendorModulesDir = "vendor/modules"
+
+// VendoredModule is a local module outside of the code directory, copied into
+// the uploaded code by LoadDir()
+type VendoredModule struct {
+	// Source is the module source as written in the code, i.e. `../modules/network`
+	Source string `json:"source" yaml:"source"`
+	// Dir is the absolute path of the module directory
+	Dir string `json:"dir" yaml:"dir"`
+	// Path is the module directory in the uploaded code, i.e. `vendor/modules/network`
+	Path string `json:"path" yaml:"path"`
+	// Files is the number of files copied
+	Files int `json:"files" yaml:"files"`
+}
+
+// VendoredModules returns the local modules outside of the code directory that
+// were copied into the code loaded by LoadDir()
+func (w *Workspace) VendoredModules() []VendoredModule {
+	return w.vendored
+}
+
+// codeFileOrigin is a Terraform file of the code and the directory it comes
+// from, used to resolve the relative module sources
+type codeFileOrigin struct {
+	name string
+	dir  string
+}
+
+// vendorModules copies into the code the local modules referenced with a
+// relative source outside of the root directory, i.e. `../modules/network`,
+// and rewrites the module sources to the new location. The modules used by
+// the vendored modules are vendored too
+func (w *Workspace) vendorModules(root string) error {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+
+	w.vendored = nil
+	var queue []codeFileOrigin
+	for _, name := range sortedKeys(w.tfCodeFiles) {
+		queue = append(queue, codeFileOrigin{name, filepath.Join(root, filepath.FromSlash(path.Dir(name)))})
+	}
+
+	for len(queue) != 0 {
+		file := queue[0]
+		queue = queue[1:]
+		if !strings.HasSuffix(file.name, ".tf") {
+			continue
+		}
+
+		f, diags := hclwrite.ParseConfig([]byte(w.tfCodeFiles[file.name]), file.name, hcl.InitialPos)
+		if diags.HasErrors() {
+			// the syntax errors are reported by Lint()
+			continue
+		}
+
+		changed := false
+		for _, block := range f.Body().Blocks() {
+			if block.Type() != "module" {
+				continue
+			}
+			source, ok := moduleSource(block)
+			if !ok || !(strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")) {
+				continue
+			}
+
+			target := filepath.Clean(filepath.Join(file.dir, filepath.FromSlash(source)))
+			location, err := w.moduleLocation(root, source, target, &queue)
+			if err != nil {
+				return err
+			}
+
+			newSource := path.Clean(relSlash(path.Dir(file.name), location))
+			if !strings.HasPrefix(newSource, "../") {
+				newSource = "./" + newSource
+			}
+			if newSource != source {
+				block.Body().SetAttributeValue("source", cty.StringVal(newSource))
+				changed = true
+			}
+		}
+		if changed {
+			w.tfCodeFiles[file.name] = string(f.Bytes())
+		}
+	}
+
+	for _, m := range w.vendored {
+		w.logPrintf("vendored module %s (%s) into %s, %d files", m.Source, m.Dir, m.Path, m.Files)
+	}
+
+	return nil
+}
+
+// moduleLocation returns the directory of the module in the code. If the module
+// is outside of the root directory, it's copied into the vendor directory and
+// its files are queued to resolve their module sources
+func (w *Workspace) moduleLocation(root, source, target string, queue *[]codeFileOrigin) (string, error) {
+	if rel, ok := within(root, target); ok {
+		return rel, nil
+	}
+	for _, m := range w.vendored {
+		if rel, ok := within(m.Dir, target); ok {
+			return path.Join(m.Path, rel), nil
+		}
+	}
+
+	files, modes, err := readDirFiles(target)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the module %q in %q. %s", source, target, err)
+	}
+
+	location := path.Join(vendorModulesDir, filepath.Base(target))
+	for i := 2; w.hasDir(location); i++ {
+		location = fmt.Sprintf("%s/%s-%d", vendorModulesDir, filepath.Base(target), i)
+	}
+
+	for _, name := range sortedKeys(files) {
+		vendoredName := path.Join(location, name)
+		w.tfCodeFiles[vendoredName] = files[name]
+		if mode, ok := modes[name]; ok {
+			w.tfFileModes[vendoredName] = mode
+		}
+		*queue = append(*queue, codeFileOrigin{vendoredName, filepath.Join(target, filepath.FromSlash(path.Dir(name)))})
+	}
+	w.vendored = append(w.vendored, VendoredModule{
+		Source: source,
+		Dir:    target,
+		Path:   location,
+		Files:  len(files),
+	})
+
+	return location, nil
+}
+
+// hasDir returns true if there is any code file in the given directory
+func (w *Workspace) hasDir(dir string) bool {
+	for name := range w.tfCodeFiles {
+		if strings.HasPrefix(name, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// moduleSource returns the source of the module block, if it's a literal string
+func moduleSource(block *hclwrite.Block) (string, bool) {
+	attr := block.Body().GetAttribute("source")
+	if attr == nil {
+		return "", false
+	}
+	expr, diags := hclsyntax.ParseExpression(attr.Expr().BuildTokens(nil).Bytes(), "source", hcl.InitialPos)
+	if diags.HasErrors() {
+		return "", false
+	}
+	v, diags := expr.Value(nil)
+	if diags.HasErrors() || v.Type() != cty.String || v.IsNull() {
+		return "", false
+	}
+	return v.AsString(), true
+}
+
+// within returns the path of target relative to dir, if target is dir or it's
+// inside dir
+func within(dir, target string) (string, bool) {
+	rel, err := filepath.Rel(dir, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// relSlash returns the relative path from one directory to other, both are
+// slash separated paths in the code
+func relSlash(from, to string) string {
+	rel, err := filepath.Rel(filepath.FromSlash(from), filepath.FromSlash(to))
+	if err != nil {
+		return to
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,79 @@
+package schematics
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkspace_LoadDir_vendorModules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gics-modules")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"live/main.tf": `module "network" {
+  source = "../modules/network"
+  name   = "gics"
+}
+
+module "local" {
+  source = "./local"
+}
+
+module "vpc" {
+  source = "terraform-ibm-modules/vpc/ibm"
+}
+`,
+		"live/local/main.tf":         `module "again" { source = "../../modules/network" }`,
+		"modules/network/main.tf":    `module "subnet" { source = "../subnet" }`,
+		"modules/network/outputs.tf": `output "id" { value = "id" }`,
+		"modules/subnet/main.tf":     `variable "name" {}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		ioutil.WriteFile(path, []byte(content), 0644)
+	}
+
+	w := New("modules", "", nil)
+	if !assert.NoError(t, w.LoadDir(filepath.Join(dir, "live"))) {
+		return
+	}
+
+	assert.Equal(t, []string{
+		"local/main.tf",
+		"main.tf",
+		"vendor/modules/network/main.tf",
+		"vendor/modules/network/outputs.tf",
+		"vendor/modules/subnet/main.tf",
+	}, sortedKeys(w.tfCodeFiles))
+	assert.Equal(t, `module "network" {
+  source = "./vendor/modules/network"
+  name   = "gics"
+}
+
+module "local" {
+  source = "./local"
+}
+
+module "vpc" {
+  source = "terraform-ibm-modules/vpc/ibm"
+}
+`, w.tfCodeFiles["main.tf"])
+	assert.Equal(t, `module "again" { source = "../vendor/modules/network" }`, w.tfCodeFiles["local/main.tf"])
+	assert.Equal(t, `module "subnet" { source = "../subnet" }`, w.tfCodeFiles["vendor/modules/network/main.tf"])
+
+	assert.Equal(t, []VendoredModule{
+		{Source: "../../modules/network", Dir: filepath.Join(dir, "modules/network"), Path: "vendor/modules/network", Files: 2},
+		{Source: "../subnet", Dir: filepath.Join(dir, "modules/subnet"), Path: "vendor/modules/subnet", Files: 1},
+	}, w.VendoredModules())
+
+	ioutil.WriteFile(filepath.Join(dir, "live/main.tf"), []byte(`module "missing" { source = "../missing" }`), 0644)
+	assert.Error(t, w.LoadDir(filepath.Join(dir, "live")), "LoadDir() should fail if a module is not found")
+}
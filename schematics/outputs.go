@@ -0,0 +1,161 @@
+package schematics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	apiv1 "github.com/johandry/gics/schematics/api/v1"
+)
+
+const (
+	getWorkspaceOutputsTimeout = 50
+)
+
+// OutputValue is a Terraform output of a Schematics workspace
+type OutputValue struct {
+	Name      string      `json:"name,omitempty" yaml:"name,omitempty"`
+	Type      string      `json:"type,omitempty" yaml:"type,omitempty"`
+	Sensitive bool        `json:"sensitive,omitempty" yaml:"sensitive,omitempty"`
+	Value     interface{} `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// Outputs gets the Terraform outputs of the workspace, decoded with their
+// declared type, and stores the values in the Output field. It's called by
+// Run() after the code is applied
+func (w *Workspace) Outputs(ctx context.Context) ([]OutputValue, error) {
+	// Outputs Timeout
+	ctx, cancelFunc := context.WithTimeout(ctx, getWorkspaceOutputsTimeout*time.Second)
+	defer cancelFunc()
+
+	params := &apiv1.GetWorkspaceOutputsParams{}
+	resp, err := w.api().clientWithResponses.GetWorkspaceOutputsWithResponse(ctx, w.ID, params)
+	if err != nil {
+		return nil, err
+	}
+	if code := resp.StatusCode(); code != 200 {
+		return nil, getAPIError("failed to get the workspace outputs", resp.Body)
+	}
+
+	outputs := []OutputValue{}
+	if resp.JSON200 != nil {
+		for _, template := range *resp.JSON200 {
+			if template.OutputValues == nil {
+				continue
+			}
+			for _, values := range *template.OutputValues {
+				for name, v := range values {
+					output, err := newOutputValue(name, v)
+					if err != nil {
+						return nil, err
+					}
+					outputs = append(outputs, output)
+				}
+			}
+		}
+	}
+	sort.Slice(outputs, func(i, j int) bool { return outputs[i].Name < outputs[j].Name })
+
+	w.Output = map[string]interface{}{}
+	for _, output := range outputs {
+		w.Output[output.Name] = output.Value
+	}
+
+	return outputs, nil
+}
+
+// DecodeOutputs decodes the workspace outputs into the given pointer to a
+// struct or map, like json.Unmarshal does. Use `json` tags in the struct fields
+// to map the output names. The outputs have to be loaded with Run() or Outputs()
+func (w *Workspace) DecodeOutputs(v interface{}) error {
+	if w.Output == nil {
+		return fmt.Errorf("the workspace %q has no outputs loaded", w.Name)
+	}
+
+	data, err := json.Marshal(w.Output)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode the workspace outputs. %s", err)
+	}
+
+	return nil
+}
+
+// newOutputValue creates an OutputValue from the output returned by the API
+// (i.e. {"sensitive": false, "type": ["list", "string"], "value": ["a", "b"]})
+func newOutputValue(name string, v interface{}) (OutputValue, error) {
+	output := OutputValue{
+		Name: name,
+	}
+
+	o, ok := v.(map[string]interface{})
+	if !ok {
+		// Not the expected format, it's just the value
+		output.Value = v
+		return output, nil
+	}
+
+	output.Type = typeExpression(o["type"])
+	if sensitive, ok := o["sensitive"].(bool); ok {
+		output.Sensitive = sensitive
+	}
+	output.Value = o["value"]
+
+	// Complex values may be returned JSON encoded
+	if s, ok := output.Value.(string); ok && output.Type != "string" && len(output.Type) != 0 {
+		var value interface{}
+		if err := json.Unmarshal([]byte(s), &value); err != nil {
+			return output, fmt.Errorf("failed to decode the output %q of type %s. %s", name, output.Type, err)
+		}
+		output.Value = value
+	}
+
+	return output, nil
+}
+
+// typeExpression returns the Terraform type expression of the JSON encoded
+// type returned by Terraform (i.e. ["map", "string"] => map(string))
+func typeExpression(t interface{}) string {
+	switch tt := t.(type) {
+	case string:
+		return tt
+	case []interface{}:
+		if len(tt) != 2 {
+			break
+		}
+		kind, _ := tt[0].(string)
+		switch kind {
+		case "list", "set", "map":
+			return fmt.Sprintf("%s(%s)", kind, typeExpression(tt[1]))
+		case "tuple":
+			elems, _ := tt[1].([]interface{})
+			types := make([]string, 0, len(elems))
+			for _, e := range elems {
+				types = append(types, typeExpression(e))
+			}
+			return fmt.Sprintf("tuple([%s])", strings.Join(types, ", "))
+		case "object":
+			attrs, _ := tt[1].(map[string]interface{})
+			names := make([]string, 0, len(attrs))
+			for name := range attrs {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			types := make([]string, 0, len(attrs))
+			for _, name := range names {
+				types = append(types, fmt.Sprintf("%s=%s", name, typeExpression(attrs[name])))
+			}
+			return fmt.Sprintf("object({%s})", strings.Join(types, ", "))
+		}
+	case nil:
+		return ""
+	}
+
+	data, _ := json.Marshal(t)
+	return string(data)
+}
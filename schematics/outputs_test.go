@@ -0,0 +1,70 @@
+package schematics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkspace_Outputs(t *testing.T) {
+	workspaceName := "outputs"
+	workspaceID := fmt.Sprintf("%s-9e3fa4b5-6c7d-8e", workspaceName)
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://schematics.cloud.ibm.com/v1/workspaces/%s/output_values", workspaceID),
+		func(req *http.Request) (*http.Response, error) {
+			// Get the fixture with the following code after getting the Token:
+			// export TOKEN=$(cat .token | jq -r .access_token)
+			// export WID=
+			// curl -X GET "https://schematics.cloud.ibm.com/v1/workspaces/$WID/output_values" -H "Authorization: Bearer $TOKEN" -H 'Accept: application/json'
+			fixture := `[{"folder":".","id":"iac-c3d4e5f6-7a8b-9c","output_values":[{"name":{"sensitive":false,"type":"string","value":"gics-demo-group"},"count":{"sensitive":false,"type":"number","value":3},"zones":{"sensitive":false,"type":["list","string"],"value":["us-south-1","us-south-2"]},"tags":{"sensitive":false,"type":["map","string"],"value":"{\"env\":\"dev\"}"},"network":{"sensitive":false,"type":["object",{"id":"string","public":"bool"}],"value":{"id":"r006-123","public":true}},"api_key":{"sensitive":true,"type":"string","value":"secret"}}],"value_type":"terraform_v0.13"}]`
+			resp := httpmock.NewStringResponse(200, fixture)
+			resp.Header.Add("Content-Type", "application/json; charset=utf-8")
+			return resp, nil
+		},
+	)
+
+	w := New(workspaceName, "", nil)
+	w.ID = workspaceID
+
+	err := w.DecodeOutputs(&struct{}{})
+	assert.Error(t, err, "DecodeOutputs() should fail if the outputs are not loaded")
+
+	outputs, err := w.Outputs(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	expected := []OutputValue{
+		{Name: "api_key", Type: "string", Sensitive: true, Value: "secret"},
+		{Name: "count", Type: "number", Value: float64(3)},
+		{Name: "name", Type: "string", Value: "gics-demo-group"},
+		{Name: "network", Type: "object({id=string, public=bool})", Value: map[string]interface{}{"id": "r006-123", "public": true}},
+		{Name: "tags", Type: "map(string)", Value: map[string]interface{}{"env": "dev"}},
+		{Name: "zones", Type: "list(string)", Value: []interface{}{"us-south-1", "us-south-2"}},
+	}
+	assert.Equal(t, expected, outputs)
+	assert.Equal(t, map[string]interface{}{"name": "gics-demo-group"}, w.GetParam("name"))
+
+	var got struct {
+		Name    string            `json:"name"`
+		Count   int               `json:"count"`
+		Zones   []string          `json:"zones"`
+		Tags    map[string]string `json:"tags"`
+		Network struct {
+			ID     string `json:"id"`
+			Public bool   `json:"public"`
+		} `json:"network"`
+	}
+	if assert.NoError(t, w.DecodeOutputs(&got)) {
+		assert.Equal(t, "gics-demo-group", got.Name)
+		assert.Equal(t, 3, got.Count)
+		assert.Equal(t, []string{"us-south-1", "us-south-2"}, got.Zones)
+		assert.Equal(t, map[string]string{"env": "dev"}, got.Tags)
+		assert.Equal(t, "r006-123", got.Network.ID)
+		assert.True(t, got.Network.Public)
+	}
+}
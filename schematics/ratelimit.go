@@ -0,0 +1,109 @@
+package schematics
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultReadRate   = 5
+	defaultReadBurst  = 10
+	defaultWriteRate  = 1
+	defaultWriteBurst = 3
+)
+
+// tokenBucket is a token bucket rate limiter. The bucket is refilled at rate
+// tokens per second up to burst tokens, every request takes a token or waits
+// until there is one
+type tokenBucket struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a full token bucket, or returns nil (no limit) if
+// the rate is not positive
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait takes a token from the bucket, blocking until there is one available
+// or the context is done. The token is reserved before waiting, so the
+// requests are served in order
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	deficit := -b.tokens
+	b.mu.Unlock()
+
+	if deficit <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(deficit / b.rate * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// give back the reserved token
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// rateLimiter limits the requests of a service, and the regional services
+// created from it, with separate budgets for the reads and the mutating
+// requests
+type rateLimiter struct {
+	read  *tokenBucket
+	write *tokenBucket
+}
+
+func newRateLimiter(opt *ServiceOptions) *rateLimiter {
+	return &rateLimiter{
+		read:  newTokenBucket(opt.ReadRate, opt.ReadBurst),
+		write: newTokenBucket(opt.WriteRate, opt.WriteBurst),
+	}
+}
+
+// Wait blocks until the request is allowed by the budget of its kind
+func (l *rateLimiter) Wait(req *http.Request) error {
+	if l == nil {
+		return nil
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return l.read.Wait(req.Context())
+	default:
+		return l.write.Wait(req.Context())
+	}
+}
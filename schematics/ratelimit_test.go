@@ -0,0 +1,45 @@
+package schematics
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket_Wait(t *testing.T) {
+	b := newTokenBucket(100, 2)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, b.Wait(ctx))
+	}
+	elapsed := time.Since(start)
+	assert.True(t, elapsed >= 15*time.Millisecond, "after the burst the requests should wait, took %s", elapsed)
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Equal(t, context.Canceled, b.Wait(ctx))
+
+	assert.Nil(t, newTokenBucket(-1, 10), "a negative rate should disable the limit")
+	assert.NoError(t, (*tokenBucket)(nil).Wait(ctx))
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	s := NewService(&ServiceOptions{ReadRate: 1000, ReadBurst: 1, WriteRate: 0.001, WriteBurst: 1})
+	assert.Equal(t, s.limiter, s.regional("eu-de").limiter, "the regional services should share the rate limiter")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	put, _ := http.NewRequestWithContext(ctx, "PUT", "https://schematics.cloud.ibm.com/v1/workspaces/limit/apply", nil)
+	assert.NoError(t, s.limiter.Wait(put))
+	get, _ := http.NewRequestWithContext(ctx, "GET", "https://schematics.cloud.ibm.com/v1/workspaces/limit", nil)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, s.limiter.Wait(get), "the reads should not use the budget of the mutating requests")
+	}
+	assert.Equal(t, context.DeadlineExceeded, s.limiter.Wait(put), "the mutating requests should wait for the budget")
+}
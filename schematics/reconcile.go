@@ -0,0 +1,590 @@
+package schematics
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	apiv1 "github.com/johandry/gics/schematics/api/v1"
+)
+
+const (
+	reconcileWorkspaceTimeout = 50
+)
+
+// ChangeAction is the action to execute on a field of an existing Schematics
+// workspace to match the workspace definition
+type ChangeAction string
+
+const (
+	// ChangeActionCreate is when the field or the workspace does not exists
+	ChangeActionCreate = ChangeAction("+")
+
+	// ChangeActionUpdate is when the field exists with a different value
+	ChangeActionUpdate = ChangeAction("~")
+
+	// ChangeActionDelete is when the field exists but it's not in the workspace definition
+	ChangeActionDelete = ChangeAction("-")
+)
+
+// Change is a difference between the workspace definition (i.e. a manifest)
+// and the existing Schematics workspace
+type Change struct {
+	Action ChangeAction `json:"action,omitempty" yaml:"action,omitempty"`
+	Field  string       `json:"field,omitempty" yaml:"field,omitempty"`
+	Old    string       `json:"old,omitempty" yaml:"old,omitempty"`
+	New    string       `json:"new,omitempty" yaml:"new,omitempty"`
+}
+
+func (c Change) String() string {
+	switch c.Action {
+	case ChangeActionCreate:
+		return fmt.Sprintf("%s %s: %q", c.Action, c.Field, c.New)
+	case ChangeActionDelete:
+		return fmt.Sprintf("%s %s: %q", c.Action, c.Field, c.Old)
+	default:
+		return fmt.Sprintf("%s %s: %q => %q", c.Action, c.Field, c.Old, c.New)
+	}
+}
+
+// immutable fields can't be updated, the workspace has to be re-created
+var immutableFields = map[string]struct{}{
+	"location":       {},
+	"resource_group": {},
+}
+
+const sensitiveValue = "(sensitive)"
+
+// Diff returns the changes to apply to the existing workspace so it matches
+// this workspace definition. Empty fields in the definition are ignored, except
+// for variables and environment values
+func (w *Workspace) Diff(existing *Workspace) []Change {
+	changes := []Change{}
+
+	diffField := func(field, old, new string) {
+		if len(new) != 0 && old != new {
+			changes = append(changes, Change{ChangeActionUpdate, field, old, new})
+		}
+	}
+
+	diffField("location", existing.Location, w.Location)
+	diffField("resource_group", existing.ResourceGroup, w.ResourceGroup)
+	diffField("description", existing.Description, w.Description)
+	diffField("type", existing.Type, w.Type)
+	diffField("folder", existing.Folder, w.Folder)
+	if w.Tags != nil {
+		diffField("tags", joinSorted(existing.Tags), joinSorted(w.Tags))
+	}
+
+	if w.GitRepo != nil {
+		existingRepo := existing.GitRepo
+		if existingRepo == nil {
+			existingRepo = &GitRepo{}
+		}
+		if w.GitRepo.URL != existingRepo.URL && w.GitRepo.URL != existing.repoFullURL {
+			diffField("git_repo.url", existing.repoFullURL, w.GitRepo.URL)
+		}
+		diffField("git_repo.branch", existingRepo.Branch, w.GitRepo.Branch)
+		diffField("git_repo.release", existingRepo.Release, w.GitRepo.Release)
+	}
+
+	changes = append(changes, diffEnvValues(existing.EnvValues, w.EnvValues)...)
+	changes = append(changes, diffVariables(existing.Variables, w.Variables)...)
+
+	return changes
+}
+
+func diffEnvValues(existing, desired []EnvVariable) []Change {
+	changes := []Change{}
+
+	existingEnv := flattenEnvValues(existing)
+	desiredEnv := flattenEnvValues(desired)
+
+	for _, name := range sortedKeys(desiredEnv) {
+		field := "env_values." + name
+		old, ok := existingEnv[name]
+		if !ok {
+			changes = append(changes, Change{ChangeActionCreate, field, "", desiredEnv[name]})
+			continue
+		}
+		if old != desiredEnv[name] {
+			changes = append(changes, Change{ChangeActionUpdate, field, old, desiredEnv[name]})
+		}
+	}
+	for _, name := range sortedKeys(existingEnv) {
+		if _, ok := desiredEnv[name]; !ok {
+			changes = append(changes, Change{ChangeActionDelete, "env_values." + name, existingEnv[name], ""})
+		}
+	}
+
+	return changes
+}
+
+func diffVariables(existing, desired []Variable) []Change {
+	changes := []Change{}
+
+	existingVars := map[string]Variable{}
+	for _, v := range existing {
+		existingVars[v.Name] = v
+	}
+	desiredVars := map[string]struct{}{}
+
+	for _, v := range desired {
+		desiredVars[v.Name] = struct{}{}
+		field := "variables." + v.Name
+
+		old, ok := existingVars[v.Name]
+		if !ok {
+			changes = append(changes, Change{ChangeActionCreate, field, "", variableValue(v)})
+			continue
+		}
+		if variableType(old) != variableType(v) {
+			changes = append(changes, Change{ChangeActionUpdate, field + ".type", variableType(old), variableType(v)})
+		}
+		if old.Secure != v.Secure {
+			changes = append(changes, Change{ChangeActionUpdate, field + ".secure", fmt.Sprint(old.Secure), fmt.Sprint(v.Secure)})
+		}
+		switch {
+		case !old.Secure && !v.Secure && old.Value != v.Value:
+			changes = append(changes, Change{ChangeActionUpdate, field, old.Value, v.Value})
+		case old.Secure && v.Secure && len(v.Value) != 0:
+			// The value of a secure variable is not returned by the API, so it
+			// can't be compared. It's always updated if it's set, i.e. to rotate
+			// an API key
+			changes = append(changes, Change{ChangeActionUpdate, field, sensitiveValue, sensitiveValue})
+		}
+	}
+	for _, v := range existing {
+		if _, ok := desiredVars[v.Name]; !ok {
+			changes = append(changes, Change{ChangeActionDelete, "variables." + v.Name, variableValue(v), ""})
+		}
+	}
+
+	return changes
+}
+
+func variableValue(v Variable) string {
+	if v.Secure {
+		return sensitiveValue
+	}
+	return v.Value
+}
+
+// variableType returns the variable type, the API may return an empty type
+// for the default type (string)
+func variableType(v Variable) string {
+	if len(v.Type) == 0 {
+		return "string"
+	}
+	return v.Type
+}
+
+// Reconcile makes the Schematics workspace match this workspace definition.
+// The workspace is found by ID or by name and resource group, then it's
+// created if it doesn't exists, or updated only with the changes from the
+// definition. It returns the applied changes, no changes means the workspace
+// was up to date. Use Plan() and Apply() after Reconcile() to apply the code
+func (w *Workspace) Reconcile() ([]Change, error) {
+	ctx, cancelFunc := context.WithTimeout(context.Background(), reconcileWorkspaceTimeout*time.Second)
+	defer cancelFunc()
+
+	if err := w.checkInputs(); err != nil {
+		return nil, err
+	}
+
+	existing, err := w.api().lookup(ctx, w.ID, w.Name, w.ResourceGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing == nil {
+		act, err := w.Create()
+		if err != nil {
+			return nil, err
+		}
+		if err := act.Wait(); err != nil {
+			return nil, err
+		}
+		if _, err := w.uploadCode(); err != nil {
+			return nil, err
+		}
+		return []Change{{ChangeActionCreate, "workspace", "", w.Name}}, nil
+	}
+
+	changes := w.Diff(existing)
+	for _, c := range changes {
+		if _, ok := immutableFields[c.Field]; ok {
+			return changes, fmt.Errorf("the %s of the workspace %q cannot be changed, it has to be deleted and created again", c.Field, existing.Name)
+		}
+	}
+
+	w.ID = existing.ID
+	w.keepOmitted(existing)
+	w.CreatedAt = existing.CreatedAt
+	w.CreatedBy = existing.CreatedBy
+	w.Status = existing.Status
+	w.StatusMessage = existing.StatusMessage
+	w.Locked = existing.Locked
+	w.LockedBy = existing.LockedBy
+	w.Frozen = existing.Frozen
+	w.templateID = existing.templateID
+	w.repoFullURL = existing.repoFullURL
+	w.remoteCodeHash = existing.remoteCodeHash
+	w.CodeCommit = existing.CodeCommit
+
+	var updateConfig, updateInputs bool
+	for _, c := range changes {
+		if strings.HasPrefix(c.Field, "variables.") || strings.HasPrefix(c.Field, "env_values.") {
+			updateInputs = true
+		} else {
+			updateConfig = true
+		}
+	}
+
+	if updateConfig || updateInputs {
+		if err := w.validateAction(WorkspaceActionUpdate); err != nil {
+			return changes, err
+		}
+	}
+	if updateConfig {
+		if err := w.updateConfig(ctx); err != nil {
+			return changes, err
+		}
+	}
+	if updateInputs {
+		if err := w.updateInputs(ctx); err != nil {
+			return changes, err
+		}
+	}
+
+	// Upload the code only if it has changed since the last upload
+	uploaded, err := w.uploadCode()
+	if err != nil {
+		return changes, err
+	}
+	if uploaded {
+		changes = append(changes, Change{ChangeActionUpdate, "code", "", w.codeSummary()})
+	}
+
+	return changes, nil
+}
+
+// Preview returns the changes that Reconcile() would apply to the Schematics
+// workspace, without changing it. The code is reported as changed only if it
+// was loaded from files and it's different from the last uploaded code
+func (w *Workspace) Preview() ([]Change, error) {
+	ctx, cancelFunc := context.WithTimeout(context.Background(), reconcileWorkspaceTimeout*time.Second)
+	defer cancelFunc()
+
+	existing, err := w.api().lookup(ctx, w.ID, w.Name, w.ResourceGroup)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return []Change{{ChangeActionCreate, "workspace", "", w.Name}}, nil
+	}
+
+	changes := w.Diff(existing)
+	if len(w.tfCodeFiles) != 0 && w.codeHash() != existing.remoteCodeHash {
+		changes = append(changes, Change{ChangeActionUpdate, "code", "", w.codeSummary()})
+	}
+	return changes, nil
+}
+
+// keepOmitted sets the fields omitted in the workspace definition with the
+// values of the existing workspace, so they are not removed by the update.
+// The location and resource group can't be updated
+func (w *Workspace) keepOmitted(existing *Workspace) {
+	if len(w.Description) == 0 {
+		w.Description = existing.Description
+	}
+	if len(w.Type) == 0 {
+		w.Type = existing.Type
+	}
+	if len(w.Folder) == 0 {
+		w.Folder = existing.Folder
+	}
+	if w.Tags == nil {
+		w.Tags = existing.Tags
+	}
+	if w.GitRepo == nil {
+		w.GitRepo = existing.GitRepo
+	} else if existing.GitRepo != nil {
+		repo := *w.GitRepo
+		if len(repo.Branch) == 0 {
+			repo.Branch = existing.GitRepo.Branch
+		}
+		if len(repo.Release) == 0 {
+			repo.Release = existing.GitRepo.Release
+		}
+		w.GitRepo = &repo
+	}
+}
+
+// lookup finds an existing workspace by ID, or by name and resource group if
+// the ID is not set. Returns nil if the workspace is not found
+func (s *Service) lookup(ctx context.Context, id, name, resourceGroup string) (*Workspace, error) {
+	if len(id) != 0 {
+		return s.Get(ctx, id)
+	}
+
+	list, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *Workspace
+	for _, summary := range list.Workspaces {
+		if summary.Name != name {
+			continue
+		}
+		w, err := s.Get(ctx, summary.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(resourceGroup) != 0 && w.ResourceGroup != resourceGroup {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("found more than one workspace named %q (%s, %s), use the workspace ID", name, found.ID, w.ID)
+		}
+		found = w
+	}
+
+	return found, nil
+}
+
+// updateConfig updates the workspace settings that are not inputs (variables
+// or environment values)
+func (w *Workspace) updateConfig(ctx context.Context) error {
+	tags := apiv1.Tags(w.tags())
+	templateData := &apiv1.TemplateData{
+		apiv1.TemplateSourceDataRequest{
+			Folder: &w.Folder,
+			Type:   &w.Type,
+		},
+	}
+	var templateRepo *apiv1.TemplateRepoUpdateRequest
+	if w.GitRepo != nil {
+		templateRepo = &apiv1.TemplateRepoUpdateRequest{
+			Branch:  &w.GitRepo.Branch,
+			Release: &w.GitRepo.Release,
+			Url:     &w.GitRepo.URL,
+		}
+	}
+	workspaceUpdateRequest := apiv1.WorkspaceUpdateRequest{
+		Description:  &w.Description,
+		Tags:         &tags,
+		TemplateData: templateData,
+		TemplateRepo: templateRepo,
+		Type:         &[]string{w.Type},
+	}
+
+	params := &apiv1.UpdateWorkspaceParams{}
+	body := apiv1.UpdateWorkspaceJSONRequestBody(apiv1.UpdateWorkspaceJSONBody(workspaceUpdateRequest))
+	resp, err := w.api().clientWithResponses.UpdateWorkspaceWithResponse(ctx, w.ID, params, body)
+	if err != nil {
+		return err
+	}
+	if code := resp.StatusCode(); code != 200 {
+		return getAPIError("failed to update the workspace", resp.Body)
+	}
+
+	// The variables are not updated here, keep the desired values
+	variables, envValues := w.Variables, w.EnvValues
+	w.update(resp.JSON200)
+	w.Variables, w.EnvValues = variables, envValues
+
+	return nil
+}
+
+// updateInputs replaces the variables and environment values of the workspace
+func (w *Workspace) updateInputs(ctx context.Context) error {
+	if err := w.checkVariablesSecrets(); err != nil {
+		return err
+	}
+
+	variables := apiv1.VariablesUpdateRequest{}
+	for i := range w.Variables {
+		v := w.Variables[i]
+		variables = append(variables, apiv1.WorkspaceVariableUpdateRequest{
+			Description: &v.Description,
+			Name:        &v.Name,
+			Secure:      &v.Secure,
+			Type:        &v.Type,
+			Value:       &v.Value,
+		})
+	}
+
+	env := flattenEnvValues(w.EnvValues)
+	envValues := []apiv1.EnvValueUpdateRequest{}
+	for _, name := range sortedKeys(env) {
+		name, value := name, env[name]
+		envValues = append(envValues, apiv1.EnvValueUpdateRequest{
+			Name:  &name,
+			Value: &value,
+		})
+	}
+
+	userValues := apiv1.UserValuesRequest{
+		EnvValues:     &envValues,
+		Values:        &w.Values,
+		Variablestore: &variables,
+	}
+
+	params := &apiv1.ReplaceWorkspaceInputsParams{}
+	body := apiv1.ReplaceWorkspaceInputsJSONRequestBody(apiv1.ReplaceWorkspaceInputsJSONBody(userValues))
+	resp, err := w.api().clientWithResponses.ReplaceWorkspaceInputsWithResponse(ctx, w.ID, w.templateID, params, body)
+	if err != nil {
+		return err
+	}
+	if code := resp.StatusCode(); code != 200 {
+		return getAPIError("failed to update the workspace variables", resp.Body)
+	}
+
+	return nil
+}
+
+// uploadCode uploads the loaded code, if any, and stores its hash in the
+// workspace tags. The code is not uploaded if it has the same hash of the code
+// already uploaded, it returns true if the code was uploaded
+func (w *Workspace) uploadCode() (bool, error) {
+	if len(w.tfCodeFiles) == 0 {
+		switch {
+		case len(w.TarCode) != 0:
+			return w.uploadTarCode()
+		case len(w.Code) != 0:
+			if err := w.LoadCode(string(w.Code)); err != nil {
+				return false, err
+			}
+		case w.tfBuf != nil:
+			// the code was loaded as a tar, there are no files to hash
+			return true, w.UploadTar(w.tfBuf)
+		default:
+			return false, nil
+		}
+	}
+
+	hash := w.codeHash()
+	if hash == w.remoteCodeHash {
+		return false, nil
+	}
+	if err := w.CheckCode(); err != nil {
+		return false, err
+	}
+
+	// the tar is streamed instead of using the loaded one, it may have been
+	// read by a previous upload
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(w.writeTar(pw))
+	}()
+	defer pr.Close()
+
+	if err := w.UploadTar(pr); err != nil {
+		return false, err
+	}
+
+	return true, w.setCodeHash(hash)
+}
+
+// uploadTarCode uploads the pre-built archive in TarCode, if it's not the
+// same archive already uploaded
+func (w *Workspace) uploadTarCode() (bool, error) {
+	sum := sha256.Sum256(w.TarCode)
+	hash := hex.EncodeToString(sum[:])
+	if hash == w.remoteCodeHash {
+		return false, nil
+	}
+	if size := int64(len(w.TarCode)); size > maxUploadSize {
+		return false, fmt.Errorf("the code archive of the workspace %q (%s) is larger than the maximum size accepted by Schematics (%s)", w.Name, humanSize(size), humanSize(maxUploadSize))
+	}
+
+	if err := w.UploadTar(nil); err != nil {
+		return false, err
+	}
+
+	return true, w.setCodeHash(hash)
+}
+
+// setCodeHash updates the workspace tags with the hash of the uploaded code
+// and the commit it was loaded from, if any
+func (w *Workspace) setCodeHash(hash string) error {
+	ctx, cancelFunc := context.WithTimeout(context.Background(), reconcileWorkspaceTimeout*time.Second)
+	defer cancelFunc()
+
+	w.remoteCodeHash = hash
+	w.CodeCommit = w.codeCommit
+	tags := apiv1.Tags(w.tags())
+	workspaceUpdateRequest := apiv1.WorkspaceUpdateRequest{
+		Tags: &tags,
+	}
+
+	params := &apiv1.UpdateWorkspaceParams{}
+	body := apiv1.UpdateWorkspaceJSONRequestBody(apiv1.UpdateWorkspaceJSONBody(workspaceUpdateRequest))
+	resp, err := w.api().clientWithResponses.UpdateWorkspaceWithResponse(ctx, w.ID, params, body)
+	if err != nil {
+		return err
+	}
+	if code := resp.StatusCode(); code != 200 {
+		return getAPIError("failed to update the workspace code hash", resp.Body)
+	}
+
+	return nil
+}
+
+// tags returns the workspace tags including the tags with the hash and the
+// commit of the uploaded code, if any
+func (w *Workspace) tags() []string {
+	tags := append([]string{}, w.Tags...)
+	if len(w.remoteCodeHash) != 0 {
+		tags = append(tags, codeHashTagPrefix+w.remoteCodeHash)
+	}
+	if len(w.CodeCommit) != 0 {
+		tags = append(tags, codeCommitTagPrefix+w.CodeCommit)
+	}
+	return tags
+}
+
+func (w *Workspace) codeSummary() string {
+	if len(w.codeCommit) != 0 {
+		return fmt.Sprintf("%s@%s", w.codePath, w.codeCommit)
+	}
+	if len(w.codePath) != 0 {
+		return w.codePath
+	}
+	if len(w.tfCodeFiles) == 0 && len(w.TarCode) != 0 {
+		return fmt.Sprintf("archive of %s", humanSize(int64(len(w.TarCode))))
+	}
+	return fmt.Sprintf("%d files", len(w.tfCodeFiles))
+}
+
+func flattenEnvValues(envValues []EnvVariable) map[string]string {
+	env := map[string]string{}
+	for _, e := range envValues {
+		for name, value := range e {
+			env[name] = value
+		}
+	}
+	return env
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinSorted(list []string) string {
+	sorted := append([]string{}, list...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
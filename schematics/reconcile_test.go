@@ -0,0 +1,333 @@
+package schematics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	apiv1 "github.com/johandry/gics/schematics/api/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkspace_Diff(t *testing.T) {
+	existing := &Workspace{
+		Name:          "workspace",
+		Location:      "us-south",
+		ResourceGroup: "Default",
+		Description:   "old description",
+		Type:          "terraform_v0.13",
+		Tags:          []string{"b", "a"},
+		EnvValues:     []EnvVariable{{"TF_LOG": "DEBUG"}, {"OLD": "1"}},
+		Variables: []Variable{
+			{Name: "prefix", Value: "gics", Type: ""},
+			{Name: "enable", Value: "true", Type: "bool"},
+			{Name: "api_key", Value: "", Type: "string", Secure: true},
+			{Name: "removed", Value: "x"},
+		},
+		GitRepo:     &GitRepo{URL: "https://github.com/IBM/cloud-enterprise-examples", Branch: "master"},
+		repoFullURL: "https://github.com/IBM/cloud-enterprise-examples/tree/master/iac/01-getting-started",
+	}
+
+	tests := []struct {
+		name    string
+		desired *Workspace
+		want    []Change
+	}{
+		{
+			"no changes",
+			&Workspace{
+				Name:      "workspace",
+				Location:  "us-south",
+				Type:      "terraform_v0.13",
+				Tags:      []string{"a", "b"},
+				EnvValues: []EnvVariable{{"TF_LOG": "DEBUG", "OLD": "1"}},
+				Variables: []Variable{
+					{Name: "prefix", Value: "gics", Type: "string"},
+					{Name: "enable", Value: "true", Type: "bool"},
+					{Name: "api_key", Value: "", Type: "string", Secure: true},
+					{Name: "removed", Value: "x"},
+				},
+				GitRepo: &GitRepo{URL: "https://github.com/IBM/cloud-enterprise-examples/tree/master/iac/01-getting-started"},
+			},
+			[]Change{},
+		},
+		{
+			"secure value",
+			&Workspace{
+				Name:      "workspace",
+				EnvValues: []EnvVariable{{"TF_LOG": "DEBUG", "OLD": "1"}},
+				Variables: []Variable{
+					{Name: "prefix", Value: "gics"},
+					{Name: "enable", Value: "true", Type: "bool"},
+					{Name: "api_key", Value: "rotated", Type: "string", Secure: true},
+					{Name: "removed", Value: "x"},
+				},
+			},
+			[]Change{
+				{ChangeActionUpdate, "variables.api_key", sensitiveValue, sensitiveValue},
+			},
+		},
+		{
+			"changes",
+			&Workspace{
+				Name:          "workspace",
+				ResourceGroup: "other",
+				Description:   "new description",
+				Type:          "terraform_v0.13",
+				EnvValues:     []EnvVariable{{"TF_LOG": "TRACE"}, {"NEW": "2"}},
+				Variables: []Variable{
+					{Name: "prefix", Value: "gics-demo"},
+					{Name: "enable", Value: "true", Type: "string"},
+					{Name: "api_key", Value: "secret", Type: "string"},
+					{Name: "token", Value: "secret", Secure: true},
+				},
+				GitRepo: &GitRepo{URL: "https://github.com/IBM/cloud-enterprise-examples", Branch: "main"},
+			},
+			[]Change{
+				{ChangeActionUpdate, "resource_group", "Default", "other"},
+				{ChangeActionUpdate, "description", "old description", "new description"},
+				{ChangeActionUpdate, "git_repo.branch", "master", "main"},
+				{ChangeActionCreate, "env_values.NEW", "", "2"},
+				{ChangeActionUpdate, "env_values.TF_LOG", "DEBUG", "TRACE"},
+				{ChangeActionDelete, "env_values.OLD", "1", ""},
+				{ChangeActionUpdate, "variables.prefix", "gics", "gics-demo"},
+				{ChangeActionUpdate, "variables.enable.type", "bool", "string"},
+				{ChangeActionUpdate, "variables.api_key.secure", "true", "false"},
+				{ChangeActionCreate, "variables.token", "", sensitiveValue},
+				{ChangeActionDelete, "variables.removed", "x", ""},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.desired.Diff(existing)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWorkspace_Diff_manifestType(t *testing.T) {
+	existing := &Workspace{Name: "workspace", Location: "us-south", Type: "terraform_v0.12"}
+
+	// Without type in the manifest, the existing type is kept
+	w, err := (&Manifest{Name: "workspace"}).workspace(defaultService, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Empty(t, w.Diff(existing))
+
+	w, err = (&Manifest{Name: "workspace", Type: "terraform_v0.13"}).workspace(defaultService, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []Change{{ChangeActionUpdate, "type", "terraform_v0.12", "terraform_v0.13"}}, w.Diff(existing))
+}
+
+func TestWorkspace_Reconcile(t *testing.T) {
+	workspaceName := "reconciled"
+	workspaceID := fmt.Sprintf("%s-5d2ab1c3-9f1e-4a", workspaceName)
+	templateID := "iac-a1b2c3d4-5e6f-7a"
+
+	workspaceFixture := fmt.Sprintf(`{"id":"%s","name":"%s","type":["terraform_v0.13"],"description":"","resource_group":"Default","location":"us-south","tags":[],"created_at":"2020-12-17T06:21:29.762423059Z","created_by":"johandry@gmail.com","status":"ACTIVE","workspace_status":{"frozen":false,"locked":false},"template_data":[{"id":"%s","folder":".","type":"terraform_v0.13","values":"","variablestore":[{"name":"prefix","secure":false,"value":"gics","type":"","description":""}],"has_githubtoken":false}]}`, workspaceID, workspaceName, templateID)
+
+	listResponder := func(req *http.Request) (*http.Response, error) {
+		fixture := fmt.Sprintf(`{"offset":0,"limit":100,"count":2,"workspaces":[{"id":"other-1234","name":"other","description":"","location":"us-south","created_by":"johandry@gmail.com","status":"ACTIVE","created_at":"2020-12-17T06:21:29.762423059Z"},%s]}`, workspaceFixture)
+		resp := httpmock.NewStringResponse(200, fixture)
+		resp.Header.Add("Content-Type", "application/json; charset=utf-8")
+		return resp, nil
+	}
+	getResponder := func(req *http.Request) (*http.Response, error) {
+		resp := httpmock.NewStringResponse(200, workspaceFixture)
+		resp.Header.Add("Content-Type", "application/json; charset=utf-8")
+		return resp, nil
+	}
+	httpmock.RegisterResponder("GET", "https://schematics.cloud.ibm.com/v1/workspaces", listResponder)
+	httpmock.RegisterResponder("GET", "https://schematics.cloud.ibm.com/v1/workspaces/"+workspaceID, getResponder)
+	// the workspace with location eu-de is looked up in the eu-de endpoint
+	httpmock.RegisterResponder("GET", "https://eu-de.schematics.cloud.ibm.com/v1/workspaces", listResponder)
+	httpmock.RegisterResponder("GET", "https://eu-de.schematics.cloud.ibm.com/v1/workspaces/"+workspaceID, getResponder)
+
+	var gotVariables []map[string]interface{}
+	httpmock.RegisterResponder("PUT", fmt.Sprintf("https://schematics.cloud.ibm.com/v1/workspaces/%s/template_data/%s/values", workspaceID, templateID),
+		func(req *http.Request) (*http.Response, error) {
+			body, _ := ioutil.ReadAll(req.Body)
+			var values struct {
+				Variablestore []map[string]interface{} `json:"variablestore"`
+			}
+			json.Unmarshal(body, &values)
+			gotVariables = values.Variablestore
+
+			resp := httpmock.NewStringResponse(200, `{}`)
+			resp.Header.Add("Content-Type", "application/json; charset=utf-8")
+			return resp, nil
+		},
+	)
+
+	// Up to date: no changes
+	w := New(workspaceName, "", nil)
+	w.AddVar("prefix", "gics", "", "", false)
+
+	changes, err := w.Reconcile()
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+	assert.Equal(t, workspaceID, w.ID)
+	assert.Equal(t, templateID, w.templateID)
+
+	// Only the variables changed
+	w = New(workspaceName, "", nil)
+	w.AddVar("prefix", "gics-demo", "", "", false)
+
+	changes, err = w.Preview()
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{{ChangeActionUpdate, "variables.prefix", "gics", "gics-demo"}}, changes)
+	assert.Nil(t, gotVariables, "Preview() should not update the workspace")
+
+	changes, err = w.Reconcile()
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{{ChangeActionUpdate, "variables.prefix", "gics", "gics-demo"}}, changes)
+	if assert.Len(t, gotVariables, 1) {
+		assert.Equal(t, "gics-demo", gotVariables[0]["value"])
+	}
+
+	// Immutable fields
+	w = New(workspaceName, "", nil)
+	w.Location = "eu-de"
+	_, err = w.Reconcile()
+	assert.EqualError(t, err, `the location of the workspace "reconciled" cannot be changed, it has to be deleted and created again`)
+}
+
+func TestWorkspace_Reconcile_keepOmitted(t *testing.T) {
+	workspaceName := "omitted"
+	workspaceID := fmt.Sprintf("%s-1a2b3c4d-5e6f-7a", workspaceName)
+	templateID := "iac-c4d5e6f7-a8b9-0c"
+
+	httpmock.RegisterResponder("GET", "https://schematics.cloud.ibm.com/v1/workspaces/"+workspaceID,
+		jsonResponder(200, fmt.Sprintf(`{"id":"%s","name":"%s","type":["terraform_v0.12"],"description":"the description","resource_group":"Default","tags":["gics","demo"],"created_by":"johandry@gmail.com","status":"ACTIVE","workspace_status":{"frozen":false,"locked":false},"template_repo":{"url":"https://github.com/IBM/cloud-enterprise-examples","branch":"master"},"template_data":[{"id":"%s","folder":"iac/01-getting-started","type":"terraform_v0.12","values":"","variablestore":[]}]}`, workspaceID, workspaceName, templateID)))
+	var got apiv1.WorkspaceUpdateRequest
+	httpmock.RegisterResponder("PATCH", "https://schematics.cloud.ibm.com/v1/workspaces/"+workspaceID,
+		func(req *http.Request) (*http.Response, error) {
+			json.NewDecoder(req.Body).Decode(&got)
+			return jsonResponder(200, fmt.Sprintf(`{"id":"%s","name":"%s","type":["terraform_v0.13"],"status":"ACTIVE"}`, workspaceID, workspaceName))(req)
+		},
+	)
+
+	// only the type is in the definition, with a Git repository without branch
+	w := New(workspaceName, "", nil)
+	w.ID = workspaceID
+	w.Type = "terraform_v0.13"
+	w.GitRepo = &GitRepo{URL: "https://github.com/IBM/cloud-enterprise-examples"}
+
+	changes, err := w.Reconcile()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []Change{{ChangeActionUpdate, "type", "terraform_v0.12", "terraform_v0.13"}}, changes)
+	if assert.NotNil(t, got.Description) {
+		assert.Equal(t, "the description", *got.Description)
+	}
+	if assert.NotNil(t, got.Tags) {
+		assert.Equal(t, apiv1.Tags{"gics", "demo"}, *got.Tags)
+	}
+	if assert.NotNil(t, got.TemplateData) && assert.Len(t, *got.TemplateData, 1) {
+		assert.Equal(t, "iac/01-getting-started", *(*got.TemplateData)[0].Folder)
+	}
+	if assert.NotNil(t, got.TemplateRepo) {
+		assert.Equal(t, "master", *got.TemplateRepo.Branch)
+	}
+}
+
+func TestWorkspace_Reconcile_create(t *testing.T) {
+	workspaceName := "created"
+	workspaceID := fmt.Sprintf("%s-3e4f5a6b-7c8d-9e", workspaceName)
+
+	httpmock.RegisterResponder("GET", "https://schematics.cloud.ibm.com/v1/workspaces",
+		jsonResponder(200, `{"offset":0,"limit":100,"count":1,"workspaces":[{"id":"other-1234","name":"other","description":"","location":"us-south","created_by":"johandry@gmail.com","status":"ACTIVE","created_at":"2020-12-17T06:21:29.762423059Z"}]}`))
+	creates := 0
+	httpmock.RegisterResponder("POST", "https://schematics.cloud.ibm.com/v1/workspaces",
+		func(req *http.Request) (*http.Response, error) {
+			creates++
+			return jsonResponder(201, fmt.Sprintf(`{"id":"%s","name":"%s","type":["terraform_v0.13"],"location":"us-south","resource_group":"Default","created_by":"johandry@gmail.com","status":"DRAFT"}`, workspaceID, workspaceName))(req)
+		},
+	)
+	// there is no activity for the workspace creation
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://us-south.schematics.cloud.ibm.com/v1/workspaces/%s/actions", workspaceID),
+		jsonResponder(200, fmt.Sprintf(`{"workspace_name":"%s","workspace_id":"%s","actions":[]}`, workspaceName, workspaceID)))
+
+	w := New(workspaceName, "", nil)
+	changes, err := w.Reconcile()
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{{ChangeActionCreate, "workspace", "", workspaceName}}, changes)
+	assert.Equal(t, 1, creates)
+	assert.Equal(t, workspaceID, w.ID)
+}
+
+func TestWorkspace_RunWithOptions_reuseExisting(t *testing.T) {
+	workspaceName := "reused"
+	workspaceID := fmt.Sprintf("%s-7c1de2f3-4a5b-6c", workspaceName)
+	templateID := "iac-b2c3d4e5-6f7a-8b"
+	code := `variable "prefix" {}`
+
+	w := New(workspaceName, "", nil)
+	w.AddVar("prefix", "gics", "", "", false)
+	w.LoadCode(code)
+	uploadedHash := w.codeHash()
+
+	workspaceFixture := func() string {
+		return fmt.Sprintf(`{"id":"%s","name":"%s","type":["terraform_v0.13"],"description":"","resource_group":"Default","location":"us-south","tags":["%s%s"],"created_at":"2020-12-17T06:21:29.762423059Z","created_by":"johandry@gmail.com","status":"FAILED","template_data":[{"id":"%s","folder":".","type":"terraform_v0.13","values":"","variablestore":[{"name":"prefix","secure":false,"value":"gics","type":"","description":""}]}]}`, workspaceID, workspaceName, codeHashTagPrefix, uploadedHash, templateID)
+	}
+	httpmock.RegisterResponder("GET", "https://schematics.cloud.ibm.com/v1/workspaces/"+workspaceID,
+		func(req *http.Request) (*http.Response, error) {
+			return jsonResponder(200, workspaceFixture())(req)
+		},
+	)
+	uploads := 0
+	httpmock.RegisterResponder("PUT", fmt.Sprintf("https://schematics.cloud.ibm.com/v1/workspaces/%s/templates/%s/template_repo_upload", workspaceID, templateID),
+		func(req *http.Request) (*http.Response, error) {
+			uploads++
+			return jsonResponder(200, `{"has_received_file":true}`)(req)
+		},
+	)
+	var gotTags []string
+	httpmock.RegisterResponder("PATCH", "https://schematics.cloud.ibm.com/v1/workspaces/"+workspaceID,
+		func(req *http.Request) (*http.Response, error) {
+			var body struct {
+				Tags []string `json:"tags"`
+			}
+			json.NewDecoder(req.Body).Decode(&body)
+			gotTags = body.Tags
+			return jsonResponder(200, workspaceFixture())(req)
+		},
+	)
+	httpmock.RegisterResponder("POST", fmt.Sprintf("https://schematics.cloud.ibm.com/v1/workspaces/%s/plan", workspaceID),
+		jsonResponder(202, `{"activityid":"plan-activity"}`))
+	httpmock.RegisterResponder("PUT", fmt.Sprintf("https://schematics.cloud.ibm.com/v1/workspaces/%s/apply", workspaceID),
+		jsonResponder(202, `{"activityid":"apply-activity"}`))
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://schematics.cloud.ibm.com/v1/workspaces/%s/output_values", workspaceID),
+		jsonResponder(200, fmt.Sprintf(`[{"folder":".","id":"%s","output_values":[{"name":{"sensitive":false,"type":"string","value":"gics-group"}}],"value_type":"terraform_v0.13"}]`, templateID)))
+	httpmock.RegisterResponder("GET", `=~^https://schematics\.cloud\.ibm\.com/v1/workspaces/`+workspaceID+`/actions/([\w-]+)\z`,
+		func(req *http.Request) (*http.Response, error) {
+			id := httpmock.MustGetSubmatch(req, 1)
+			return jsonResponder(200, fmt.Sprintf(`{"action_id":"%s","name":"PLAN","status":"COMPLETED","performed_by":"johandry@gmail.com"}`, id))(req)
+		},
+	)
+
+	// Same code: it's not uploaded again
+	w.ID = workspaceID
+	err := w.RunWithOptions(&RunOptions{ReuseExisting: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, uploads, "the unchanged code should not be uploaded")
+	assert.Equal(t, map[string]interface{}{"name": "gics-group"}, w.GetParam("name"))
+
+	// The code changed: it's uploaded and the new hash is stored in the tags
+	w = New(workspaceName, "", nil)
+	w.ID = workspaceID
+	w.AddVar("prefix", "gics", "", "", false)
+	w.LoadCode(code + "\n# new line")
+	err = w.RunWithOptions(&RunOptions{ReuseExisting: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, uploads, "the changed code should be uploaded")
+	assert.Equal(t, []string{codeHashTagPrefix + w.codeHash()}, gotTags)
+}
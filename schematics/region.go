@@ -0,0 +1,152 @@
+package schematics
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	apiv1 "github.com/johandry/gics/schematics/api/v1"
+)
+
+const (
+	listLocationsTimeout = 50
+)
+
+// regionRe is the format of the region names, used in the endpoint host name
+var regionRe = regexp.MustCompile(`^[a-z]+(-[a-z0-9]+)*$`)
+
+// Location is a location where Schematics workspaces can be created
+type Location struct {
+	ID             string `json:"id,omitempty" yaml:"id,omitempty"`
+	Name           string `json:"name,omitempty" yaml:"name,omitempty"`
+	Kind           string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	Country        string `json:"country,omitempty" yaml:"country,omitempty"`
+	Geography      string `json:"geography,omitempty" yaml:"geography,omitempty"`
+	Metro          string `json:"metro,omitempty" yaml:"metro,omitempty"`
+	MultizoneMetro string `json:"multizone_metro,omitempty" yaml:"multizone_metro,omitempty"`
+}
+
+// endpoint returns the public or private Schematics endpoint of the region,
+// or the global endpoint if the region is empty
+func endpoint(region string, private bool) string {
+	switch {
+	case len(region) == 0 && private:
+		return defaultPrivateAPIEndpoint
+	case len(region) == 0:
+		return defaultAPIEndpoint
+	case private:
+		return fmt.Sprintf("https://private-%s.schematics.cloud.ibm.com", region)
+	default:
+		return fmt.Sprintf("https://%s.schematics.cloud.ibm.com", region)
+	}
+}
+
+// Locations returns the locations where Schematics workspaces can be created.
+// The locations are requested once per service
+func (s *Service) Locations(ctx context.Context) ([]Location, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locations != nil {
+		return s.locations, nil
+	}
+
+	// Locations Timeout
+	ctx, cancelFunc := context.WithTimeout(ctx, listLocationsTimeout*time.Second)
+	defer cancelFunc()
+
+	resp, err := s.clientWithResponses.ListSchematicsLocationWithResponse(ctx, &apiv1.ListSchematicsLocationParams{})
+	if err != nil {
+		return nil, err
+	}
+	if code := resp.StatusCode(); code != 200 {
+		return nil, getAPIError("failed to list the Schematics locations", resp.Body)
+	}
+
+	locations := []Location{}
+	if resp.JSON200 != nil {
+		for _, l := range *resp.JSON200 {
+			locations = append(locations, Location{
+				ID:             stringValue(l.Id),
+				Name:           stringValue(l.Name),
+				Kind:           stringValue(l.Kind),
+				Country:        stringValue(l.Country),
+				Geography:      stringValue(l.Geography),
+				Metro:          stringValue(l.Metro),
+				MultizoneMetro: stringValue(l.MultizoneMetro),
+			})
+		}
+	}
+	s.locations = locations
+
+	return locations, nil
+}
+
+// ValidateRegion verifies the region is one of the Schematics locations
+func (s *Service) ValidateRegion(ctx context.Context, region string) error {
+	locations, err := s.Locations(ctx)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(locations))
+	for _, l := range locations {
+		if l.ID == region {
+			return nil
+		}
+		ids = append(ids, l.ID)
+	}
+	return fmt.Errorf("unknown Schematics region %q, the available regions are: %s", region, strings.Join(ids, ", "))
+}
+
+// regional returns the service for the endpoint of the given region, it
+// shares the options, authenticator and rate limiter of this service. If the service
+// endpoint was set with BaseURL or Region, or the region is not valid, the
+// same service is returned
+func (s *Service) regional(region string) *Service {
+	if s.pinned || len(region) == 0 || region == s.region || !regionRe.MatchString(region) {
+		return s
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rs, ok := s.regions[region]; ok {
+		return rs
+	}
+
+	opt := s.options
+	opt.Region = region
+	opt.BaseURL = endpoint(region, opt.Private)
+	rs := newService(&opt, s.authenticator, s.limiter)
+	rs.pinned = true
+
+	if s.regions == nil {
+		s.regions = map[string]*Service{}
+	}
+	s.regions[region] = rs
+
+	return rs
+}
+
+// regionOf returns the region of a workspace ID, the IDs start with the
+// region, i.e. `us-south.workspace.name.1a2b3c4d`
+func regionOf(id string) string {
+	if i := strings.Index(id, ".workspace."); i > 0 {
+		return id[:i]
+	}
+	return ""
+}
+
+// region returns the region of the workspace, from the location or the ID
+func (w *Workspace) region() string {
+	if len(w.Location) != 0 {
+		return w.Location
+	}
+	return regionOf(w.ID)
+}
+
+// api returns the service to reach the workspace, in its region
+func (w *Workspace) api() *Service {
+	return w.service.regional(w.region())
+}
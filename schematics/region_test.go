@@ -0,0 +1,118 @@
+package schematics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewService_endpoint(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  *ServiceOptions
+		want string
+	}{
+		{"global", nil, "https://schematics.cloud.ibm.com/"},
+		{"global private", &ServiceOptions{Private: true}, "https://private-us.schematics.cloud.ibm.com/"},
+		{"region", &ServiceOptions{Region: "eu-de"}, "https://eu-de.schematics.cloud.ibm.com/"},
+		{"region private", &ServiceOptions{Region: "us-east", Private: true}, "https://private-us-east.schematics.cloud.ibm.com/"},
+		{"base URL", &ServiceOptions{BaseURL: "http://localhost:8080", Region: "eu-gb"}, "http://localhost:8080/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewService(tt.opt)
+			assert.Equal(t, tt.want, s.client.Server)
+		})
+	}
+}
+
+func TestWorkspace_api(t *testing.T) {
+	s := NewService(nil)
+
+	w := New("region", "", s)
+	assert.Equal(t, s, w.api(), "the workspace without location should use the global endpoint")
+
+	w.Location = "eu-de"
+	rs := w.api()
+	assert.Equal(t, "https://eu-de.schematics.cloud.ibm.com/", rs.client.Server)
+	assert.Equal(t, rs, w.api(), "the regional service should be reused")
+	assert.Equal(t, s.authenticator, rs.authenticator, "the regional service should share the authenticator")
+
+	w.Location = ""
+	w.ID = "us-east.workspace.region.1a2b3c4d"
+	assert.Equal(t, "https://us-east.schematics.cloud.ibm.com/", w.api().client.Server)
+
+	pinned := NewService(&ServiceOptions{Region: "eu-gb", Private: true})
+	w = New("region", "", pinned)
+	w.Location = "eu-de"
+	assert.Equal(t, pinned, w.api(), "the workspace should use the endpoint set in the service")
+}
+
+func TestWorkspace_Outputs_region(t *testing.T) {
+	workspaceID := "eu-de.workspace.region.9e3fa4b5"
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://eu-de.schematics.cloud.ibm.com/v1/workspaces/%s/output_values", workspaceID),
+		jsonResponder(200, `[{"folder":".","id":"iac-c3d4e5f6-7a8b-9c","output_values":[{"name":{"sensitive":false,"type":"string","value":"gics"}}],"value_type":"terraform_v0.13"}]`))
+
+	w := New("region", "", NewService(nil))
+	w.ID = workspaceID
+	outputs, err := w.Outputs(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, outputs, 1)
+}
+
+func TestService_ValidateRegion(t *testing.T) {
+	httpmock.RegisterResponder("GET", "https://private-us.schematics.cloud.ibm.com/v1/locations",
+		jsonResponder(200, `[{"id":"us-south","name":"Dallas","kind":"region","geography":"North America","country":"United States","metro":"Dallas"},{"id":"eu-de","name":"Frankfurt","kind":"region","geography":"Europe","country":"Germany","metro":"Frankfurt"}]`))
+
+	s := NewService(&ServiceOptions{Private: true})
+	locations, err := s.Locations(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, Location{ID: "eu-de", Name: "Frankfurt", Kind: "region", Country: "Germany", Geography: "Europe", Metro: "Frankfurt"}, locations[1])
+
+	assert.NoError(t, s.ValidateRegion(context.Background(), "eu-de"))
+	err = s.ValidateRegion(context.Background(), "mars-1")
+	if assert.Error(t, err) {
+		assert.Equal(t, `unknown Schematics region "mars-1", the available regions are: us-south, eu-de`, err.Error())
+	}
+}
+
+func TestWorkspace_Create_region(t *testing.T) {
+	workspaceName := "regional"
+	workspaceID := fmt.Sprintf("eu-de.workspace.%s.5f6a7b8c", workspaceName)
+
+	creates := 0
+	httpmock.RegisterResponder("POST", "https://eu-de.schematics.cloud.ibm.com/v1/workspaces",
+		func(req *http.Request) (*http.Response, error) {
+			creates++
+			return jsonResponder(201, fmt.Sprintf(`{"id":"%s","name":"%s","type":["terraform_v0.13"],"location":"eu-de","resource_group":"Default","created_by":"johandry@gmail.com","status":"DRAFT"}`, workspaceID, workspaceName))(req)
+		},
+	)
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://eu-de.schematics.cloud.ibm.com/v1/workspaces/%s/actions", workspaceID),
+		jsonResponder(200, fmt.Sprintf(`{"workspace_name":"%s","workspace_id":"%s","actions":[]}`, workspaceName, workspaceID)))
+
+	// the default service is not pinned, the workspace is created in its region
+	w := New(workspaceName, "", nil)
+	w.Location = "eu-de"
+	_, err := w.Create()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 1, creates, "the workspace should be created in the eu-de endpoint")
+	assert.Equal(t, workspaceID, w.ID)
+
+	w = New(workspaceName, "", nil)
+	w.Location = "mars-1"
+	_, err = w.Create()
+	if assert.Error(t, err) {
+		assert.Equal(t, `unknown Schematics region "mars-1", the available regions are: us-south, us-east, eu-gb, eu-de`, err.Error())
+	}
+	assert.Equal(t, 1, creates, "the workspace in an unknown region should not be created")
+}
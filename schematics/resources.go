@@ -0,0 +1,169 @@
+package schematics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	apiv1 "github.com/johandry/gics/schematics/api/v1"
+)
+
+const (
+	listWorkspaceResourcesTimeout = 50
+)
+
+// ResourceKind is the kind of a resource in a Schematics workspace
+type ResourceKind string
+
+const (
+	// ResourceKindManaged is a resource created and managed by the Terraform code
+	ResourceKindManaged = ResourceKind("managed")
+
+	// ResourceKindNull is a `null_resource` of the Terraform code
+	ResourceKindNull = ResourceKind("null")
+
+	// ResourceKindRelated is an IBM Cloud resource associated with the workspace
+	// but not managed by the Terraform code
+	ResourceKindRelated = ResourceKind("related")
+)
+
+// Resource is a resource created by the Terraform code of a Schematics workspace
+type Resource struct {
+	Address       string       `json:"address,omitempty" yaml:"address,omitempty"`
+	Type          string       `json:"type,omitempty" yaml:"type,omitempty"`
+	Name          string       `json:"name,omitempty" yaml:"name,omitempty"`
+	ID            string       `json:"id,omitempty" yaml:"id,omitempty"`
+	CRN           string       `json:"crn,omitempty" yaml:"crn,omitempty"`
+	Provider      string       `json:"provider,omitempty" yaml:"provider,omitempty"`
+	Status        string       `json:"status,omitempty" yaml:"status,omitempty"`
+	ResourceGroup string       `json:"resource_group,omitempty" yaml:"resource_group,omitempty"`
+	ControllerURL string       `json:"controller_url,omitempty" yaml:"controller_url,omitempty"`
+	Tainted       bool         `json:"tainted,omitempty" yaml:"tainted,omitempty"`
+	Kind          ResourceKind `json:"kind,omitempty" yaml:"kind,omitempty"`
+}
+
+// ListResources returns the list of resources created by the workspace,
+// including the null and related resources. If types are given, only the
+// resources of these types are returned
+func (w *Workspace) ListResources(types ...string) ([]Resource, error) {
+	// ListResources Timeout
+	ctx, cancelFunc := context.WithTimeout(context.Background(), listWorkspaceResourcesTimeout*time.Second)
+	defer cancelFunc()
+
+	params := &apiv1.GetWorkspaceResourcesParams{}
+	resp, err := w.api().clientWithResponses.GetWorkspaceResourcesWithResponse(ctx, w.ID, params)
+	if err != nil {
+		return nil, err
+	}
+	if code := resp.StatusCode(); code != 200 {
+		return nil, getAPIError("failed to list the workspace resources", resp.Body)
+	}
+
+	resources := []Resource{}
+	if resp.JSON200 == nil {
+		return resources, nil
+	}
+
+	for _, template := range *resp.JSON200 {
+		for kind, list := range map[ResourceKind]*[]map[string]interface{}{
+			ResourceKindManaged: template.Resources,
+			ResourceKindNull:    template.NullResources,
+			ResourceKindRelated: template.RelatedResources,
+		} {
+			if list == nil {
+				continue
+			}
+			for _, r := range *list {
+				resource := newResource(r, kind)
+				if matchType(resource.Type, types) {
+					resources = append(resources, resource)
+				}
+			}
+		}
+	}
+	sortResources(resources)
+
+	return resources, nil
+}
+
+// newResource creates a Resource from a resource returned by the API
+func newResource(r map[string]interface{}, kind ResourceKind) Resource {
+	get := func(keys ...string) string {
+		for _, key := range keys {
+			if v, ok := r[key]; ok && v != nil {
+				return fmt.Sprintf("%v", v)
+			}
+		}
+		return ""
+	}
+
+	resource := Resource{
+		Address:       get("resource_address", "address"),
+		Type:          get("resource_type", "type"),
+		Name:          get("resource_name", "name"),
+		ID:            get("resource_id", "id"),
+		CRN:           get("resource_crn", "crn"),
+		Provider:      get("resource_provider", "provider"),
+		Status:        get("resource_status", "status"),
+		ResourceGroup: get("resource_group_name", "resource_group"),
+		ControllerURL: get("resource_controller_url"),
+		Kind:          kind,
+	}
+	if tainted, ok := r["resource_tainted"].(bool); ok {
+		resource.Tainted = tainted
+	}
+
+	if kind == ResourceKindNull && len(resource.Type) == 0 {
+		resource.Type = "null_resource"
+	}
+	if len(resource.Address) == 0 && len(resource.Type) != 0 && len(resource.Name) != 0 {
+		resource.Address = resource.Type + "." + resource.Name
+	}
+	// The provider is the prefix of the resource type (i.e. ibm_is_vpc => ibm)
+	if len(resource.Provider) == 0 && len(resource.Type) != 0 {
+		resource.Provider = strings.SplitN(resource.Type, "_", 2)[0]
+	}
+
+	return resource
+}
+
+func matchType(resourceType string, types []string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		if t == resourceType {
+			return true
+		}
+	}
+	return false
+}
+
+// sortResources sorts the resources by kind (managed, null and related) and address
+func sortResources(resources []Resource) {
+	order := map[ResourceKind]int{
+		ResourceKindManaged: 0,
+		ResourceKindNull:    1,
+		ResourceKindRelated: 2,
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		if order[resources[i].Kind] != order[resources[j].Kind] {
+			return order[resources[i].Kind] < order[resources[j].Kind]
+		}
+		return resources[i].Address < resources[j].Address
+	})
+}
+
+// countManaged returns the number of resources managed by the Terraform code,
+// the related resources are not counted
+func countManaged(resources []Resource) int {
+	count := 0
+	for _, r := range resources {
+		if r.Kind != ResourceKindRelated {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,80 @@
+package schematics
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkspace_ListResources(t *testing.T) {
+	workspaceName := "resources"
+	workspaceID := fmt.Sprintf("%s-4a5b6c7d-8e9f-0a", workspaceName)
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://schematics.cloud.ibm.com/v1/workspaces/%s/resources", workspaceID),
+		func(req *http.Request) (*http.Response, error) {
+			// Get the fixture with the following code after getting the Token:
+			// export TOKEN=$(cat .token | jq -r .access_token)
+			// export WID=
+			// curl -X GET "https://schematics.cloud.ibm.com/v1/workspaces/$WID/resources" -H "Authorization: Bearer $TOKEN" -H 'Accept: application/json'
+			fixture := `[{"folder":".","id":"iac-d4e5f6a7-8b9c-0d","null_resources":[{"resource_name":"wait","resource_id":"5577006791947779410"}],"related_resources":[{"resource_name":"default","resource_type":"ibm_resource_group","resource_crn":"crn:v1:bluemix:public:resource-controller::a/1234::resource-group:5678"}],"resources":[{"resource_controller_url":"https://cloud.ibm.com/vpc-ext/network/vpcs","resource_crn":"crn:v1:bluemix:public:is:us-south:a/1234::vpc:r006-123","resource_group_name":"default","resource_id":"r006-123","resource_name":"main","resource_status":"available","resource_tainted":false,"resource_type":"ibm_is_vpc"},{"resource_crn":"crn:v1:bluemix:public:is:us-south-1:a/1234::subnet:0717-456","resource_group_name":"default","resource_id":"0717-456","resource_name":"app","resource_status":"available","resource_tainted":true,"resource_type":"ibm_is_subnet"}],"resources_count":2,"template_type":"terraform_v0.13"}]`
+			resp := httpmock.NewStringResponse(200, fixture)
+			resp.Header.Add("Content-Type", "application/json; charset=utf-8")
+			return resp, nil
+		},
+	)
+
+	w := New(workspaceName, "", nil)
+	w.ID = workspaceID
+
+	resources, err := w.ListResources()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	expected := []Resource{
+		{Address: "ibm_is_subnet.app", Type: "ibm_is_subnet", Name: "app", ID: "0717-456", CRN: "crn:v1:bluemix:public:is:us-south-1:a/1234::subnet:0717-456", Provider: "ibm", Status: "available", ResourceGroup: "default", Tainted: true, Kind: ResourceKindManaged},
+		{Address: "ibm_is_vpc.main", Type: "ibm_is_vpc", Name: "main", ID: "r006-123", CRN: "crn:v1:bluemix:public:is:us-south:a/1234::vpc:r006-123", Provider: "ibm", Status: "available", ResourceGroup: "default", ControllerURL: "https://cloud.ibm.com/vpc-ext/network/vpcs", Kind: ResourceKindManaged},
+		{Address: "null_resource.wait", Type: "null_resource", Name: "wait", ID: "5577006791947779410", Provider: "null", Kind: ResourceKindNull},
+		{Address: "ibm_resource_group.default", Type: "ibm_resource_group", Name: "default", CRN: "crn:v1:bluemix:public:resource-controller::a/1234::resource-group:5678", Provider: "ibm", Kind: ResourceKindRelated},
+	}
+	assert.Equal(t, expected, resources)
+	assert.Equal(t, 3, countManaged(resources))
+
+	resources, err = w.ListResources("ibm_is_vpc")
+	if assert.NoError(t, err) && assert.Len(t, resources, 1) {
+		assert.Equal(t, "ibm_is_vpc.main", resources[0].Address)
+	}
+}
+
+func TestWorkspace_Delete_destroy(t *testing.T) {
+	workspaceName := "destroyed"
+	workspaceID := fmt.Sprintf("%s-9e8d7c6b-5a4f-3e", workspaceName)
+	baseURL := "https://schematics.cloud.ibm.com/v1/workspaces/" + workspaceID
+
+	httpmock.RegisterResponder("GET", baseURL,
+		jsonResponder(200, fmt.Sprintf(`{"id":"%s","name":"%s","status":"ACTIVE","created_by":"johandry@gmail.com","workspace_status":{"frozen":false,"locked":false}}`, workspaceID, workspaceName)))
+	httpmock.RegisterResponder("GET", baseURL+"/resources",
+		jsonResponder(200, `[{"folder":".","id":"iac-d4e5f6a7-8b9c-0d","resources":[{"resource_name":"main","resource_type":"ibm_is_vpc","resource_id":"r006-123","provider":"ibm"}]}]`))
+	var gotDestroy string
+	httpmock.RegisterResponder("DELETE", baseURL,
+		func(req *http.Request) (*http.Response, error) {
+			gotDestroy = req.URL.Query().Get("destroyResources")
+			return jsonResponder(200, `"deleted"`)(req)
+		},
+	)
+	// the only DESTROY activity was performed by another user
+	httpmock.RegisterResponder("GET", baseURL+"/actions",
+		jsonResponder(200, fmt.Sprintf(`{"workspace_name":"%s","workspace_id":"%s","actions":[{"action_id":"0a1b2c3d4e5f60718293a4b5c6d7e8f9","name":"DESTROY","status":"IN PROGRESS","performed_by":"someone@example.com"}]}`, workspaceName, workspaceID)))
+
+	w := New(workspaceName, "", nil)
+	w.ID = workspaceID
+	w.CreatedBy = "johandry@gmail.com"
+
+	err := w.Delete(true)
+	assert.NoError(t, err, "Delete(true) should not fail without a matching activity")
+	assert.Equal(t, "true", gotDestroy, "the resources should be destroyed")
+	assert.Equal(t, WorkspaceStatusDestroyed, w.Status)
+}
@@ -0,0 +1,88 @@
+package schematics
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMaxRetries   = 3
+	defaultRetryWaitMin = 1 * time.Second
+	defaultRetryWaitMax = 30 * time.Second
+)
+
+// retryable returns true, and the reason, if the request can be retried. A
+// request is retried if the API is throttling the requests (429), or if it's
+// a read (GET, HEAD or OPTIONS) that failed with a network error or a server
+// error (5xx). The Schematics commands, like plan or apply, use PUT, they are
+// not retried on a server error because they may have started. A request
+// with a body that cannot be read again, like the streamed code upload, is
+// never retried
+func retryable(req *http.Request, resp *http.Response, err error) (string, bool) {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return "", false
+	}
+	if req.Context().Err() != nil {
+		return "", false
+	}
+
+	read := req.Method == http.MethodGet || req.Method == http.MethodHead || req.Method == http.MethodOptions
+	if err != nil {
+		return err.Error(), read
+	}
+
+	switch code := resp.StatusCode; {
+	case code == http.StatusTooManyRequests:
+		return resp.Status, true
+	case code >= 500 && code != http.StatusNotImplemented:
+		return resp.Status, read
+	}
+	return "", false
+}
+
+// backoff returns the time to wait before the retry of the given attempt,
+// starting from 0. The wait time grows exponentially from RetryWaitMin up to
+// RetryWaitMax, with a random jitter of up to a half of it so the clients
+// don't retry at the same time. If the API sets Retry-After it's used instead
+func (c *ICClient) backoff(attempt int, resp *http.Response) time.Duration {
+	if wait, ok := retryAfter(resp); ok {
+		return wait
+	}
+
+	wait := c.retryWaitMin
+	for i := 0; i < attempt && wait < c.retryWaitMax; i++ {
+		wait *= 2
+	}
+	if wait > c.retryWaitMax {
+		wait = c.retryWaitMax
+	}
+	if half := int64(wait / 2); half > 0 {
+		wait = time.Duration(half + rand.Int63n(half+1))
+	}
+	return wait
+}
+
+// retryAfter returns the time to wait set by the API in the Retry-After
+// header, in seconds or as a date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	value := resp.Header.Get("Retry-After")
+	if len(value) == 0 {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
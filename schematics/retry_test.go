@@ -0,0 +1,113 @@
+package schematics
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingResponder fails with the given status the first n requests, then
+// it responds OK
+func failingResponder(n, code int, header http.Header, calls *int) httpmock.Responder {
+	return func(req *http.Request) (*http.Response, error) {
+		*calls++
+		if *calls <= n {
+			resp := httpmock.NewStringResponse(code, `{"messagekey":"M1000_Retry"}`)
+			for k, v := range header {
+				resp.Header[k] = v
+			}
+			return resp, nil
+		}
+		return jsonResponder(200, `{}`)(req)
+	}
+}
+
+func TestICClient_Do_retry(t *testing.T) {
+	url := "https://retry.schematics.cloud.ibm.com/v1/retry"
+	tests := []struct {
+		name      string
+		method    string
+		body      io.Reader
+		fails     int
+		code      int
+		header    http.Header
+		wantCode  int
+		wantCalls int
+	}{
+		{"read server error", "GET", nil, 2, 502, nil, 200, 3},
+		{"read budget exhausted", "GET", nil, 5, 503, nil, 503, 4},
+		{"not implemented", "GET", nil, 1, 501, nil, 501, 1},
+		{"client error", "GET", nil, 1, 404, nil, 404, 1},
+		{"command server error", "PUT", bytes.NewReader([]byte(`{}`)), 1, 502, nil, 502, 1},
+		{"command throttled", "POST", bytes.NewReader([]byte(`{}`)), 2, 429, http.Header{"Retry-After": {"0"}}, 200, 3},
+		{"streamed body", "POST", io.MultiReader(strings.NewReader(`{}`)), 1, 429, nil, 429, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			httpmock.RegisterResponder(tt.method, url, failingResponder(tt.fails, tt.code, tt.header, &calls))
+
+			var out bytes.Buffer
+			s := NewService(&ServiceOptions{RetryWaitMin: time.Millisecond, RetryWaitMax: 4 * time.Millisecond, Output: &out})
+			req, _ := http.NewRequest(tt.method, url, tt.body)
+			resp, err := s.client.Client.Do(req)
+			if !assert.NoError(t, err) {
+				return
+			}
+			resp.Body.Close()
+			assert.Equal(t, tt.wantCode, resp.StatusCode)
+			assert.Equal(t, tt.wantCalls, calls)
+			assert.Equal(t, tt.wantCalls-1, strings.Count(out.String(), "[DEBUG] "), "every retry should be logged, got %q", out.String())
+		})
+	}
+}
+
+func TestICClient_Do_noRetries(t *testing.T) {
+	url := "https://retry.schematics.cloud.ibm.com/v1/no-retries"
+	calls := 0
+	httpmock.RegisterResponder("GET", url, failingResponder(1, 503, nil, &calls))
+
+	s := NewService(&ServiceOptions{MaxRetries: -1})
+	req, _ := http.NewRequest("GET", url, nil)
+	resp, err := s.client.Client.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 503, resp.StatusCode)
+	assert.Equal(t, 1, calls)
+}
+
+func TestICClient_Do_canceled(t *testing.T) {
+	url := "https://retry.schematics.cloud.ibm.com/v1/canceled"
+	calls := 0
+	httpmock.RegisterResponder("GET", url, failingResponder(5, 429, http.Header{"Retry-After": {"60"}}, &calls))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	s := NewService(nil)
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	_, err := s.client.Client.Do(req)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestICClient_backoff(t *testing.T) {
+	c := &ICClient{retryWaitMin: time.Second, retryWaitMax: 5 * time.Second}
+	for attempt, max := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		wait := c.backoff(attempt, nil)
+		assert.True(t, wait >= max/2 && wait <= max, "attempt %d should wait between %s and %s, got %s", attempt, max/2, max, wait)
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": {"7"}}}
+	assert.Equal(t, 7*time.Second, c.backoff(0, resp))
+	resp.Header.Set("Retry-After", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+	assert.Equal(t, time.Duration(0), c.backoff(0, resp))
+}
@@ -0,0 +1,280 @@
+package schematics
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// SecretScanMode is what to do when a secret is found in the code or in the
+// non-secure variables before they are sent to Schematics
+type SecretScanMode string
+
+const (
+	// SecretScanBlock fails the action if a secret is found, it's the default
+	SecretScanBlock = SecretScanMode("block")
+
+	// SecretScanWarn logs the secrets found and continues
+	SecretScanWarn = SecretScanMode("warn")
+
+	// SecretScanOff disables the secret scanning
+	SecretScanOff = SecretScanMode("off")
+)
+
+// SecretKind is the kind of secret found by ScanSecrets()
+type SecretKind string
+
+const (
+	// SecretPrivateKey is a PEM encoded private key
+	SecretPrivateKey = SecretKind("private key")
+
+	// SecretIBMAPIKey is an IBM Cloud API key
+	SecretIBMAPIKey = SecretKind("IBM Cloud API key")
+
+	// SecretHighEntropy is a random looking string, like a password or token
+	SecretHighEntropy = SecretKind("high entropy string")
+)
+
+// secretAllowComment is the comment to put in the lines of the code with a
+// string that is not a secret but looks like one
+const secretAllowComment = "gics:allow-secret"
+
+// minSecretLen is the minimum length of the strings verified for entropy
+const minSecretLen = 20
+
+// entropy thresholds, in bits per character, of the strings made of hex and
+// base64 characters. Random hex strings are also IDs and digests, so they are
+// only verified if they are assigned to a key or variable with a secret name
+const (
+	hexEntropyLimit    = 3.0
+	base64EntropyLimit = 4.5
+)
+
+var (
+	privateKeyRe = regexp.MustCompile(`-----BEGIN ([A-Z0-9]+ )*PRIVATE KEY( BLOCK)?-----`)
+	ibmAPIKeyRe  = regexp.MustCompile(`(?i)(api[_-]?key|apikey|iam[_-]?token|password|passwd|token)["']?\s*[:=]\s*["']?([A-Za-z0-9_-]{44})\b`)
+	apiKeyRe     = regexp.MustCompile(`^[A-Za-z0-9_-]{44}$`)
+	quotedRe     = regexp.MustCompile(`["']([A-Za-z0-9+/=_-]{20,})["']`)
+	tokenRe      = regexp.MustCompile(`^[A-Za-z0-9+/=_-]+$`)
+	hexRe        = regexp.MustCompile(`^[A-Fa-f0-9]+$`)
+	secretNameRe = regexp.MustCompile(`(?i)(api[_-]?key|secret|token|passw(or)?d|credential|private[_-]?key)`)
+	sshPublicRe  = regexp.MustCompile(`^(ssh-(rsa|dss|ed25519)|ecdsa-sha2-\S+|sk-\S+) AAAA`)
+	keyRe        = regexp.MustCompile(`([A-Za-z0-9_-]+)["']?\s*[:=]\s*$`)
+	// idRe matches the known shapes of IDs that look random but are not
+	// secrets: GUIDs, IBM Cloud resource IDs (a GUID with a prefix, i.e.
+	// r006-...) and Git commit SHAs
+	idRe = regexp.MustCompile(`^(([a-z0-9]{4}-)?[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-f]{40})$`)
+)
+
+// SecretFinding is a secret found in the code or in a non-secure variable by
+// ScanSecrets(). The secret is redacted, only its first characters are kept
+type SecretFinding struct {
+	Kind     SecretKind `json:"kind" yaml:"kind"`
+	File     string     `json:"file,omitempty" yaml:"file,omitempty"`
+	Line     int        `json:"line,omitempty" yaml:"line,omitempty"`
+	Variable string     `json:"variable,omitempty" yaml:"variable,omitempty"`
+	Secret   string     `json:"secret" yaml:"secret"`
+}
+
+func (f SecretFinding) String() string {
+	if len(f.Variable) != 0 {
+		return fmt.Sprintf("variable %q: %s %s", f.Variable, f.Kind, f.Secret)
+	}
+	return fmt.Sprintf("%s:%d: %s %s", f.File, f.Line, f.Kind, f.Secret)
+}
+
+// ScanSecrets looks for private keys, IBM Cloud API keys and high entropy
+// strings in the loaded code, or the pre-built archive in TarCode, and in the
+// values of the non-secure variables. The code and the variables are scanned
+// before they are sent to Schematics, the action fails if a secret is found
+// unless SecretScan is `warn` or `off`. The lines of the code with the comment
+// `gics:allow-secret` are not scanned, and the variables with a secret value
+// should be marked as Secure
+func (w *Workspace) ScanSecrets() ([]SecretFinding, error) {
+	findings, err := w.scanCode()
+	if err != nil {
+		return nil, err
+	}
+	return append(findings, w.scanVariables()...), nil
+}
+
+// scanCode scans the loaded code or the pre-built archive in TarCode
+func (w *Workspace) scanCode() ([]SecretFinding, error) {
+	files := w.tfCodeFiles
+	if len(files) == 0 && len(w.TarCode) != 0 {
+		var r io.Reader = bytes.NewReader(w.TarCode)
+		if bytes.HasPrefix(w.TarCode, gzipMagic) {
+			gr, err := gzip.NewReader(r)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the code archive. %s", err)
+			}
+			defer gr.Close()
+			r = gr
+		}
+		var err error
+		if files, _, err = readTarFiles(r); err != nil {
+			return nil, fmt.Errorf("failed to read the code archive. %s", err)
+		}
+	}
+
+	findings := []SecretFinding{}
+	for _, name := range sortedKeys(files) {
+		if strings.IndexByte(files[name], 0) != -1 {
+			// binary file
+			continue
+		}
+		for i, line := range strings.Split(files[name], "\n") {
+			if strings.Contains(line, secretAllowComment) {
+				continue
+			}
+			for _, f := range scanLine(line) {
+				f.File, f.Line = name, i+1
+				findings = append(findings, f)
+			}
+		}
+	}
+	return findings, nil
+}
+
+// scanVariables scans the values of the non-secure variables
+func (w *Workspace) scanVariables() []SecretFinding {
+	findings := []SecretFinding{}
+	for _, v := range w.Variables {
+		if v.Secure {
+			continue
+		}
+		value := strings.TrimSpace(v.Value)
+		var kind SecretKind
+		switch {
+		case privateKeyRe.MatchString(value):
+			kind = SecretPrivateKey
+		case sshPublicRe.MatchString(value):
+			continue
+		case apiKeyRe.MatchString(value) && entropy(value) > hexEntropyLimit:
+			kind = SecretIBMAPIKey
+		case highEntropy(value, secretNameRe.MatchString(v.Name)):
+			kind = SecretHighEntropy
+		default:
+			for _, f := range scanLine(value) {
+				f.Variable = v.Name
+				findings = append(findings, f)
+			}
+			continue
+		}
+		findings = append(findings, SecretFinding{Kind: kind, Variable: v.Name, Secret: redact(value)})
+	}
+	return findings
+}
+
+// scanLine looks for secrets in a line of text, one finding per secret
+func scanLine(line string) []SecretFinding {
+	var findings []SecretFinding
+	if m := privateKeyRe.FindString(line); len(m) != 0 {
+		return []SecretFinding{{Kind: SecretPrivateKey, Secret: m}}
+	}
+	if sshPublicRe.MatchString(strings.Trim(strings.TrimSpace(line), `"'`)) {
+		return nil
+	}
+
+	reported := map[string]bool{}
+	for _, m := range ibmAPIKeyRe.FindAllStringSubmatch(line, -1) {
+		reported[m[2]] = true
+		findings = append(findings, SecretFinding{Kind: SecretIBMAPIKey, Secret: redact(m[2])})
+	}
+	for _, m := range quotedRe.FindAllStringSubmatchIndex(line, -1) {
+		value := line[m[2]:m[3]]
+		if reported[value] || !highEntropy(value, secretAssignment(line[:m[0]])) {
+			continue
+		}
+		findings = append(findings, SecretFinding{Kind: SecretHighEntropy, Secret: redact(value)})
+	}
+	return findings
+}
+
+// secretAssignment returns true if the text before a value ends with the
+// assignment to a key with a secret name, i.e. `github_token = `
+func secretAssignment(prefix string) bool {
+	m := keyRe.FindStringSubmatch(prefix)
+	return m != nil && secretNameRe.MatchString(m[1])
+}
+
+// highEntropy returns true if the string looks like a random token, it has
+// only hex or base64 characters and their entropy is over the limit. The hex
+// strings are verified only if they are in a secret context, and the known
+// IDs are never a secret
+func highEntropy(s string, secretContext bool) bool {
+	if len(s) < minSecretLen || !tokenRe.MatchString(s) || idRe.MatchString(s) {
+		return false
+	}
+	if hexRe.MatchString(s) {
+		return secretContext && entropy(s) > hexEntropyLimit
+	}
+	return entropy(s) > base64EntropyLimit
+}
+
+// entropy returns the Shannon entropy of the string, in bits per character
+func entropy(s string) float64 {
+	freq := map[rune]float64{}
+	for _, r := range s {
+		freq[r]++
+	}
+	var e float64
+	n := float64(len(s))
+	for _, c := range freq {
+		p := c / n
+		e -= p * math.Log2(p)
+	}
+	return e
+}
+
+// redact keeps the first characters of the secret, enough to identify it
+func redact(s string) string {
+	if len(s) <= 8 {
+		return "****"
+	}
+	return s[:4] + "****"
+}
+
+// checkSecrets fails, warns or does nothing with the secrets found according
+// to SecretScan
+func (w *Workspace) checkSecrets(findings []SecretFinding) error {
+	if len(findings) == 0 || w.SecretScan == SecretScanOff {
+		return nil
+	}
+	if w.SecretScan == SecretScanWarn {
+		for _, f := range findings {
+			w.logPrintf("possible secret found in the workspace %q, %s", w.Name, f)
+		}
+		return nil
+	}
+
+	lines := make([]string, 0, len(findings))
+	for _, f := range findings {
+		lines = append(lines, f.String())
+	}
+	return fmt.Errorf("possible secrets found in the workspace %q, mark the variables as secure, remove the secrets from the code or set the secret scan to %q:\n%s", w.Name, SecretScanWarn, strings.Join(lines, "\n"))
+}
+
+// checkCodeSecrets scans the code before it's uploaded
+func (w *Workspace) checkCodeSecrets() error {
+	if w.SecretScan == SecretScanOff {
+		return nil
+	}
+	findings, err := w.scanCode()
+	if err != nil {
+		return err
+	}
+	return w.checkSecrets(findings)
+}
+
+// checkVariablesSecrets scans the non-secure variables before they are sent
+func (w *Workspace) checkVariablesSecrets() error {
+	if w.SecretScan == SecretScanOff {
+		return nil
+	}
+	return w.checkSecrets(w.scanVariables())
+}
@@ -3,8 +3,13 @@ package schematics
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
 	"net/http"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/IBM/go-sdk-core/core"
@@ -15,10 +20,11 @@ import (
 //go:generate go run github.com/deepmap/oapi-codegen/cmd/oapi-codegen --package=v1 --generate types,client -o ./api/v1/client.gen.go ./api/v1/schematics.json
 
 const (
-	defaultAPIEndpoint = "https://schematics.cloud.ibm.com"
-	defaultAPIVersion  = "v1"
-	userAgent          = "GICS"
-	timeout            = 20 // Seconds, longest timeout. Set shorter timeouts with context
+	defaultAPIEndpoint        = "https://schematics.cloud.ibm.com"
+	defaultPrivateAPIEndpoint = "https://private-us.schematics.cloud.ibm.com"
+	defaultAPIVersion         = "v1"
+	userAgent                 = "GICS"
+	timeout                   = 20 // Seconds, longest timeout. Set shorter timeouts with context
 )
 
 // Service is the Schematics service
@@ -26,7 +32,21 @@ type Service struct {
 	client              *apiv1.Client
 	clientWithResponses *apiv1.ClientWithResponses
 	apiVersion          string
-	authenticator       *core.IamAuthenticator
+	authenticator       core.Authenticator
+	limiter             *rateLimiter
+
+	// options are the options used to create the service, and the regional
+	// services used to reach the workspaces in other regions
+	options ServiceOptions
+	// region is the region of the endpoint, it's empty for the global endpoint
+	region string
+	// pinned is true if the endpoint was set with BaseURL or Region, so the
+	// requests are not routed to the workspace region
+	pinned bool
+
+	mu        sync.Mutex
+	regions   map[string]*Service
+	locations []Location
 }
 
 // ServiceOptions are the parameters to pass to create a new Schematics Service
@@ -35,6 +55,42 @@ type ServiceOptions struct {
 	HTTPClient *http.Client
 	APIKey     string
 	APIVersion string
+	// Authenticator is the go-sdk-core authenticator of the requests, i.e. a
+	// bearer token or trusted profile authenticator, or a no-auth
+	// authenticator for a local stand-in server. By default the requests are
+	// authenticated with the IAM API Key, from APIKey or the environment
+	// variable IC_API_KEY, or with the IAM token in IC_IAM_TOKEN
+	Authenticator core.Authenticator
+	// Region is the region of the Schematics endpoint to use, i.e. `us-south`
+	// or `eu-de`. If Region and BaseURL are empty, the requests for a
+	// workspace are sent to the endpoint of the workspace location
+	Region string
+	// Private is true to use the private endpoints, only reachable from the
+	// IBM Cloud private network
+	Private bool
+	// MaxRetries is the maximum number of retries of a failed request, 3 by
+	// default. Set a negative number to disable the retries
+	MaxRetries int
+	// RetryWaitMin and RetryWaitMax are the minimum and maximum time to wait
+	// before a retry, 1 and 30 seconds by default. The wait time grows
+	// exponentially with every retry, unless the API sets Retry-After
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
+	// Output is used for the debug logs of the requests, i.e. the retries. It
+	// won't log by default
+	Output io.Writer
+	// Trace is used to dump every request and response, with the secrets
+	// redacted. It's os.Stderr if the environment variable GICS_TRACE is `1`
+	Trace io.Writer
+	// ReadRate and WriteRate are the maximum number of reads (GET) and
+	// mutating requests per second, 5 and 1 by default, with bursts of up to
+	// ReadBurst and WriteBurst requests, 10 and 3 by default. The budgets are
+	// shared by all the workspaces and activities using the service, in every
+	// region. Set a negative rate to disable the limit
+	ReadRate   float64
+	ReadBurst  int
+	WriteRate  float64
+	WriteBurst int
 }
 
 var defaultService = NewService(nil)
@@ -45,8 +101,9 @@ func NewService(opt *ServiceOptions) *Service {
 	if opt == nil {
 		opt = &ServiceOptions{}
 	}
+	pinned := len(opt.BaseURL) != 0 || len(opt.Region) != 0
 	if len(opt.BaseURL) == 0 {
-		opt.BaseURL = defaultAPIEndpoint
+		opt.BaseURL = endpoint(opt.Region, opt.Private)
 	}
 	if opt.HTTPClient == nil {
 		schHTTPpClient := &http.Client{
@@ -55,22 +112,62 @@ func NewService(opt *ServiceOptions) *Service {
 		opt.HTTPClient = schHTTPpClient
 	}
 
-	// Get API Key
-	if len(opt.APIKey) == 0 {
-		opt.APIKey = os.Getenv("IC_API_KEY")
-	}
-
 	if len(opt.APIVersion) == 0 {
 		opt.APIVersion = defaultAPIVersion
 	}
 
-	authenticator := &core.IamAuthenticator{
-		ApiKey: opt.APIKey,
+	switch {
+	case opt.MaxRetries == 0:
+		opt.MaxRetries = defaultMaxRetries
+	case opt.MaxRetries < 0:
+		opt.MaxRetries = 0
+	}
+	if opt.RetryWaitMin == 0 {
+		opt.RetryWaitMin = defaultRetryWaitMin
+	}
+	if opt.RetryWaitMax == 0 {
+		opt.RetryWaitMax = defaultRetryWaitMax
+	}
+
+	if opt.ReadRate == 0 {
+		opt.ReadRate = defaultReadRate
+	}
+	if opt.ReadBurst == 0 {
+		opt.ReadBurst = defaultReadBurst
 	}
+	if opt.WriteRate == 0 {
+		opt.WriteRate = defaultWriteRate
+	}
+	if opt.WriteBurst == 0 {
+		opt.WriteBurst = defaultWriteBurst
+	}
+
+	if opt.Trace == nil && os.Getenv("GICS_TRACE") == "1" {
+		opt.Trace = os.Stderr
+	}
+
+	if opt.Authenticator == nil {
+		opt.Authenticator = defaultAuthenticator(opt.APIKey, opt.HTTPClient)
+	}
+
+	s := newService(opt, opt.Authenticator, newRateLimiter(opt))
+	s.pinned = pinned
+	return s
+}
+
+// newService creates the Schematics service with the given options, already
+// completed, authenticator and rate limiter
+func newService(opt *ServiceOptions, authenticator core.Authenticator, limiter *rateLimiter) *Service {
 	icc := &ICClient{
 		UserAgent:     userAgent,
 		http:          opt.HTTPClient,
 		authenticator: authenticator,
+		maxRetries:    opt.MaxRetries,
+		retryWaitMin:  opt.RetryWaitMin,
+		retryWaitMax:  opt.RetryWaitMax,
+		logOutput:     opt.Output,
+		trace:         opt.Trace,
+		limiter:       limiter,
 	}
 
 	c, _ := apiv1.NewClient(opt.BaseURL, apiv1.WithHTTPClient(icc))
@@ -81,7 +178,27 @@ func NewService(opt *ServiceOptions) *Service {
 		clientWithResponses: cwr,
 		apiVersion:          "/" + opt.APIVersion,
 		authenticator:       authenticator,
+		limiter:             limiter,
+		options:             *opt,
+		region:              opt.Region,
+	}
+}
+
+// defaultAuthenticator returns the IAM authenticator for the API Key, from
+// the parameter or the environment variable IC_API_KEY. Without API Key, it
+// returns a bearer token authenticator if there is an IAM token in the
+// environment variable IC_IAM_TOKEN
+func defaultAuthenticator(apiKey string, client *http.Client) core.Authenticator {
+	if len(apiKey) == 0 {
+		apiKey = os.Getenv("IC_API_KEY")
+	}
+	if token := os.Getenv("IC_IAM_TOKEN"); len(apiKey) == 0 && len(token) != 0 {
+		// the token printed by `ibmcloud iam oauth-tokens` has the type
+		return &core.BearerTokenAuthenticator{
+			BearerToken: strings.TrimPrefix(token, "Bearer "),
+		}
 	}
+	return newIAMAuthenticator(apiKey, client)
 }
 
 // ICClient is an HTTP Client wrapped by the Schematics client to communicate
@@ -89,22 +206,78 @@ func NewService(opt *ServiceOptions) *Service {
 type ICClient struct {
 	UserAgent     string
 	http          *http.Client
-	authenticator *core.IamAuthenticator
+	authenticator core.Authenticator
+	maxRetries    int
+	retryWaitMin  time.Duration
+	retryWaitMax  time.Duration
+	logOutput     io.Writer
+	trace         io.Writer
+	limiter       *rateLimiter
 }
 
-// Do implements the Do method so ICClient is a HttpRequestDoer interface
+// Do implements the Do method so ICClient is a HttpRequestDoer interface. The
+// failed requests are retried as defined in the service options, see retry()
 func (c *ICClient) Do(req *http.Request) (*http.Response, error) {
-	if req.Body != nil {
+	if req.Body != nil && len(req.Header.Get("Content-Type")) == 0 {
 		req.Header.Set("Content-Type", "application/json")
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("User-Agent", c.UserAgent)
 
-	if err := c.authenticator.Authenticate(req); err != nil {
-		return nil, err
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		if err := c.authenticator.Authenticate(req); err != nil {
+			return nil, err
+		}
+
+		if err := c.limiter.Wait(req); err != nil {
+			return nil, err
+		}
+
+		if c.trace != nil {
+			c.traceRequest(req, attempt)
+		}
+		start := time.Now()
+		resp, err := c.http.Do(req)
+		if c.trace != nil {
+			c.traceResponse(resp, err, time.Since(start))
+		}
+		reason, ok := retryable(req, resp, err)
+		if !ok || attempt >= c.maxRetries {
+			return resp, err
+		}
+
+		wait := c.backoff(attempt, resp)
+		c.logPrintf("retry %d/%d of %s %s after %s, waiting %s", attempt+1, c.maxRetries, req.Method, req.URL.Path, reason, wait)
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
 	}
+}
 
-	return c.http.Do(req)
+// logPrintf prints a debug log if the service has an output for them
+func (c *ICClient) logPrintf(format string, v ...interface{}) {
+	if c.logOutput == nil {
+		return
+	}
+	logger := log.New(c.logOutput, "[DEBUG] ", log.Ldate|log.Ltime)
+	logger.Printf(format, v...)
 }
 
 func stringValue(s *string) string {
@@ -114,6 +287,13 @@ func stringValue(s *string) string {
 	return *s
 }
 
+func boolValue(b *bool) bool {
+	if b == nil {
+		return false
+	}
+	return *b
+}
+
 // APIError encapsulate a returned error from the API
 type APIError struct {
 	Message string                  `json:"message,omitempty"`
@@ -138,13 +318,3 @@ func getAPIError(msg string, body []byte) error {
 
 	return fmt.Errorf("%s", jsonAPIError)
 }
-
-func (w *Workspace) getToken() (string, error) {
-	req, _ := http.NewRequest("GET", "", nil)
-	if err := w.service.authenticator.Authenticate(req); err != nil {
-		return "", err
-	}
-	token := req.Header.Get("Authorization")
-
-	return token, nil
-}
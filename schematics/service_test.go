@@ -0,0 +1,54 @@
+package schematics
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/IBM/go-sdk-core/core"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewService_authenticator(t *testing.T) {
+	url := "https://auth.schematics.cloud.ibm.com/v1/version"
+	var authorization []string
+	httpmock.RegisterResponder("GET", url, func(req *http.Request) (*http.Response, error) {
+		authorization = req.Header["Authorization"]
+		return jsonResponder(200, `{}`)(req)
+	})
+
+	apiKey, token := os.Getenv("IC_API_KEY"), os.Getenv("IC_IAM_TOKEN")
+	defer func() {
+		os.Setenv("IC_API_KEY", apiKey)
+		os.Setenv("IC_IAM_TOKEN", token)
+	}()
+	os.Unsetenv("IC_API_KEY")
+	os.Setenv("IC_IAM_TOKEN", "Bearer env-token")
+
+	tests := []struct {
+		name string
+		opt  *ServiceOptions
+		want []string
+	}{
+		{"no auth", &ServiceOptions{Authenticator: &core.NoAuthAuthenticator{}}, nil},
+		{"bearer token", &ServiceOptions{Authenticator: &core.BearerTokenAuthenticator{BearerToken: "token"}}, []string{"Bearer token"}},
+		{"IAM token in the environment", nil, []string{"Bearer env-token"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authorization = nil
+			s := NewService(tt.opt)
+			req, _ := http.NewRequest("GET", url, nil)
+			resp, err := s.client.Client.Do(req)
+			if !assert.NoError(t, err) {
+				return
+			}
+			resp.Body.Close()
+			assert.Equal(t, tt.want, authorization)
+		})
+	}
+
+	s := NewService(&ServiceOptions{APIKey: "api-key"})
+	assert.IsType(t, &iamAuthenticator{}, s.authenticator, "the API Key should have precedence over the IAM token")
+}
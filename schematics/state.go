@@ -0,0 +1,305 @@
+package schematics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	apiv1 "github.com/johandry/gics/schematics/api/v1"
+)
+
+const (
+	getWorkspaceStateTimeout = 50
+)
+
+// State is the Terraform state of a Schematics workspace
+type State struct {
+	Raw              []byte                 `json:"-" yaml:"-"`
+	Version          int                    `json:"version" yaml:"version"`
+	TerraformVersion string                 `json:"terraform_version,omitempty" yaml:"terraform_version,omitempty"`
+	Serial           int64                  `json:"serial" yaml:"serial"`
+	Lineage          string                 `json:"lineage,omitempty" yaml:"lineage,omitempty"`
+	Outputs          map[string]StateOutput `json:"outputs,omitempty" yaml:"outputs,omitempty"`
+	Resources        []StateResource        `json:"resources,omitempty" yaml:"resources,omitempty"`
+}
+
+// StateOutput is an output value stored in the Terraform state
+type StateOutput struct {
+	Value     interface{} `json:"value,omitempty" yaml:"value,omitempty"`
+	Type      interface{} `json:"type,omitempty" yaml:"type,omitempty"`
+	Sensitive bool        `json:"sensitive,omitempty" yaml:"sensitive,omitempty"`
+}
+
+// StateResource is a resource stored in the Terraform state
+type StateResource struct {
+	Address   string          `json:"address" yaml:"address"`
+	Module    string          `json:"module,omitempty" yaml:"module,omitempty"`
+	Mode      string          `json:"mode" yaml:"mode"`
+	Type      string          `json:"type" yaml:"type"`
+	Name      string          `json:"name" yaml:"name"`
+	Provider  string          `json:"provider,omitempty" yaml:"provider,omitempty"`
+	Instances []StateInstance `json:"instances,omitempty" yaml:"instances,omitempty"`
+}
+
+// StateInstance is an instance of a resource stored in the Terraform state, a
+// resource has multiple instances when `count` or `for_each` is used
+type StateInstance struct {
+	Address       string                 `json:"address" yaml:"address"`
+	IndexKey      interface{}            `json:"index_key,omitempty" yaml:"index_key,omitempty"`
+	SchemaVersion int                    `json:"schema_version,omitempty" yaml:"schema_version,omitempty"`
+	Attributes    map[string]interface{} `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	Dependencies  []string               `json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
+}
+
+// State gets the Terraform state of the workspace. The returned State has the
+// raw tfstate in the Raw field, and the parsed resources, instances and outputs
+func (w *Workspace) State(ctx context.Context) (*State, error) {
+	// State Timeout
+	ctx, cancelFunc := context.WithTimeout(ctx, getWorkspaceStateTimeout*time.Second)
+	defer cancelFunc()
+
+	tID := w.templateID
+	if len(tID) == 0 {
+		var err error
+		if tID, err = w.stateTemplateID(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	params := &apiv1.GetWorkspaceTemplateStateParams{}
+	resp, err := w.api().clientWithResponses.GetWorkspaceTemplateStateWithResponse(ctx, w.ID, tID, params)
+	if err != nil {
+		return nil, err
+	}
+	if code := resp.StatusCode(); code != 200 {
+		return nil, getAPIError("failed to get the workspace state", resp.Body)
+	}
+
+	return ParseState(resp.Body)
+}
+
+// stateTemplateID returns the ID of the template with a state store, used
+// when the workspace was not loaded from the API
+func (w *Workspace) stateTemplateID(ctx context.Context) (string, error) {
+	params := &apiv1.GetWorkspaceStateParams{}
+	resp, err := w.api().clientWithResponses.GetWorkspaceStateWithResponse(ctx, w.ID, params)
+	if err != nil {
+		return "", err
+	}
+	if code := resp.StatusCode(); code != 200 {
+		return "", getAPIError("failed to get the workspace state stores", resp.Body)
+	}
+	if resp.JSON200 != nil && resp.JSON200.RuntimeData != nil {
+		for _, data := range *resp.JSON200.RuntimeData {
+			if id := stringValue(data.Id); len(id) != 0 {
+				return id, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("the workspace %q has no state", w.Name)
+}
+
+// rawState is the tfstate file format. Version 4 (Terraform 0.12+) has the
+// resources, version 3 (Terraform 0.11) has the modules
+type rawState struct {
+	Version          int                    `json:"version"`
+	TerraformVersion string                 `json:"terraform_version"`
+	Serial           int64                  `json:"serial"`
+	Lineage          string                 `json:"lineage"`
+	Outputs          map[string]StateOutput `json:"outputs"`
+	Resources        []struct {
+		Module    string `json:"module"`
+		Mode      string `json:"mode"`
+		Type      string `json:"type"`
+		Name      string `json:"name"`
+		Provider  string `json:"provider"`
+		Instances []struct {
+			IndexKey      interface{}            `json:"index_key"`
+			SchemaVersion int                    `json:"schema_version"`
+			Attributes    map[string]interface{} `json:"attributes"`
+			Dependencies  []string               `json:"dependencies"`
+		} `json:"instances"`
+	} `json:"resources"`
+	Modules []struct {
+		Path      []string               `json:"path"`
+		Outputs   map[string]StateOutput `json:"outputs"`
+		Resources map[string]struct {
+			Type      string   `json:"type"`
+			Provider  string   `json:"provider"`
+			DependsOn []string `json:"depends_on"`
+			Primary   struct {
+				ID         string                 `json:"id"`
+				Attributes map[string]interface{} `json:"attributes"`
+			} `json:"primary"`
+		} `json:"resources"`
+	} `json:"modules"`
+}
+
+// ParseState parses the content of a tfstate file
+func ParseState(data []byte) (*State, error) {
+	var raw rawState
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse the Terraform state. %s", err)
+	}
+
+	state := &State{
+		Raw:              data,
+		Version:          raw.Version,
+		TerraformVersion: raw.TerraformVersion,
+		Serial:           raw.Serial,
+		Lineage:          raw.Lineage,
+		Outputs:          raw.Outputs,
+		Resources:        []StateResource{},
+	}
+	if state.Outputs == nil {
+		state.Outputs = map[string]StateOutput{}
+	}
+
+	for _, r := range raw.Resources {
+		resource := StateResource{
+			Module:   r.Module,
+			Mode:     r.Mode,
+			Type:     r.Type,
+			Name:     r.Name,
+			Provider: r.Provider,
+		}
+		resource.Address = resourceAddress(r.Module, r.Mode, r.Type, r.Name)
+		for _, i := range r.Instances {
+			resource.Instances = append(resource.Instances, StateInstance{
+				Address:       resource.Address + indexKey(i.IndexKey),
+				IndexKey:      i.IndexKey,
+				SchemaVersion: i.SchemaVersion,
+				Attributes:    i.Attributes,
+				Dependencies:  i.Dependencies,
+			})
+		}
+		state.Resources = append(state.Resources, resource)
+	}
+
+	// Version 3: the resources are in a map "[data.]TYPE.NAME[.INDEX]" per module
+	for _, m := range raw.Modules {
+		module := ""
+		if len(m.Path) > 1 {
+			module = "module." + strings.Join(m.Path[1:], ".module.")
+		}
+		if module == "" {
+			for name, output := range m.Outputs {
+				state.Outputs[name] = output
+			}
+		}
+
+		resources := map[string]*StateResource{}
+		order := []string{}
+		keys := make([]string, 0, len(m.Resources))
+		for key := range m.Resources {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			r := m.Resources[key]
+			mode := "managed"
+			parts := strings.Split(key, ".")
+			if parts[0] == "data" {
+				mode, parts = "data", parts[1:]
+			}
+			if len(parts) < 2 {
+				continue
+			}
+			var index interface{}
+			if len(parts) > 2 {
+				var n int
+				if _, err := fmt.Sscanf(parts[2], "%d", &n); err == nil {
+					index = n
+				}
+			}
+			address := resourceAddress(module, mode, parts[0], parts[1])
+			resource, ok := resources[address]
+			if !ok {
+				resource = &StateResource{
+					Address:  address,
+					Module:   module,
+					Mode:     mode,
+					Type:     parts[0],
+					Name:     parts[1],
+					Provider: r.Provider,
+				}
+				resources[address] = resource
+				order = append(order, address)
+			}
+			attributes := r.Primary.Attributes
+			if attributes == nil {
+				attributes = map[string]interface{}{}
+			}
+			if _, ok := attributes["id"]; !ok && len(r.Primary.ID) != 0 {
+				attributes["id"] = r.Primary.ID
+			}
+			resource.Instances = append(resource.Instances, StateInstance{
+				Address:      address + indexKey(index),
+				IndexKey:     index,
+				Attributes:   attributes,
+				Dependencies: r.DependsOn,
+			})
+		}
+		for _, address := range order {
+			state.Resources = append(state.Resources, *resources[address])
+		}
+	}
+
+	return state, nil
+}
+
+// Resource returns the resource with the given address, i.e.
+// `module.network.ibm_is_vpc.main`
+func (s *State) Resource(address string) (*StateResource, bool) {
+	for i := range s.Resources {
+		if s.Resources[i].Address == address {
+			return &s.Resources[i], true
+		}
+	}
+	return nil, false
+}
+
+// Instance returns the resource instance with the given address, i.e.
+// `ibm_is_subnet.app[0]`. The index can be omitted if the resource has only one
+// instance
+func (s *State) Instance(address string) (*StateInstance, bool) {
+	for _, r := range s.Resources {
+		for i := range r.Instances {
+			if r.Instances[i].Address == address {
+				return &r.Instances[i], true
+			}
+		}
+		if r.Address == address && len(r.Instances) == 1 {
+			return &r.Instances[0], true
+		}
+	}
+	return nil, false
+}
+
+func resourceAddress(module, mode, resourceType, name string) string {
+	address := resourceType + "." + name
+	if mode == "data" {
+		address = "data." + address
+	}
+	if len(module) != 0 {
+		address = module + "." + address
+	}
+	return address
+}
+
+func indexKey(key interface{}) string {
+	switch k := key.(type) {
+	case nil:
+		return ""
+	case string:
+		return fmt.Sprintf("[%q]", k)
+	case float64:
+		return fmt.Sprintf("[%d]", int64(k))
+	default:
+		return fmt.Sprintf("[%v]", k)
+	}
+}
@@ -0,0 +1,104 @@
+package schematics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkspace_State(t *testing.T) {
+	workspaceName := "state"
+	workspaceID := fmt.Sprintf("%s-5b6c7d8e-9f0a-1b", workspaceName)
+	templateID := "iac-e5f6a7b8-9c0d-1e"
+
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://schematics.cloud.ibm.com/v1/workspaces/%s/state_stores", workspaceID),
+		func(req *http.Request) (*http.Response, error) {
+			fixture := fmt.Sprintf(`{"runtime_data":[{"engine_name":"terraform","engine_version":"v0.12.20","id":"%s","state_store_url":"https://us-south.schematics.cloud.ibm.com/v1/workspaces/%s/runtime_data/%s/state_store"}]}`, templateID, workspaceID, templateID)
+			resp := httpmock.NewStringResponse(200, fixture)
+			resp.Header.Add("Content-Type", "application/json; charset=utf-8")
+			return resp, nil
+		},
+	)
+
+	fixture := `{"version":4,"terraform_version":"0.12.20","serial":7,"lineage":"b6f0a9f2-1c2d-3e4f-5a6b-7c8d9e0f1a2b","outputs":{"name":{"value":"gics-demo-group","type":"string"}},"resources":[{"mode":"data","type":"ibm_resource_group","name":"default","provider":"provider.ibm","instances":[{"schema_version":0,"attributes":{"id":"5678","name":"default"}}]},{"mode":"managed","type":"ibm_resource_group","name":"group","provider":"provider.ibm","instances":[{"schema_version":0,"attributes":{"id":"1234","name":"gics-demo-group","state":"ACTIVE"}}]},{"module":"module.network","mode":"managed","type":"ibm_is_subnet","name":"app","provider":"provider.ibm","instances":[{"index_key":0,"schema_version":0,"attributes":{"id":"0717-456"},"dependencies":["ibm_resource_group.group"]},{"index_key":1,"schema_version":0,"attributes":{"id":"0717-789"}}]}]}`
+	httpmock.RegisterResponder("GET", fmt.Sprintf("https://schematics.cloud.ibm.com/v1/workspaces/%s/runtime_data/%s/state_store", workspaceID, templateID),
+		func(req *http.Request) (*http.Response, error) {
+			// Get the fixture with the following code after getting the Token:
+			// export TOKEN=$(cat .token | jq -r .access_token)
+			// export WID=
+			// export TID=
+			// curl -X GET "https://schematics.cloud.ibm.com/v1/workspaces/$WID/runtime_data/$TID/state_store" -H "Authorization: Bearer $TOKEN" -H 'Accept: application/json'
+			resp := httpmock.NewStringResponse(200, fixture)
+			resp.Header.Add("Content-Type", "application/json; charset=utf-8")
+			return resp, nil
+		},
+	)
+
+	w := New(workspaceName, "", nil)
+	w.ID = workspaceID
+
+	state, err := w.State(context.Background())
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []byte(fixture), state.Raw)
+	assert.Equal(t, 4, state.Version)
+	assert.Equal(t, int64(7), state.Serial)
+	assert.Equal(t, "b6f0a9f2-1c2d-3e4f-5a6b-7c8d9e0f1a2b", state.Lineage)
+	assert.Equal(t, map[string]StateOutput{"name": {Value: "gics-demo-group", Type: "string"}}, state.Outputs)
+
+	var addresses []string
+	for _, r := range state.Resources {
+		addresses = append(addresses, r.Address)
+	}
+	assert.Equal(t, []string{"data.ibm_resource_group.default", "ibm_resource_group.group", "module.network.ibm_is_subnet.app"}, addresses)
+
+	instance, ok := state.Instance("ibm_resource_group.group")
+	if assert.True(t, ok) {
+		assert.Equal(t, "ACTIVE", instance.Attributes["state"])
+	}
+	instance, ok = state.Instance("module.network.ibm_is_subnet.app[1]")
+	if assert.True(t, ok) {
+		assert.Equal(t, "0717-789", instance.Attributes["id"])
+	}
+	_, ok = state.Instance("module.network.ibm_is_subnet.app")
+	assert.False(t, ok, "Instance() should require the index of a resource with multiple instances")
+
+	resource, ok := state.Resource("module.network.ibm_is_subnet.app")
+	if assert.True(t, ok) {
+		assert.Len(t, resource.Instances, 2)
+		assert.Equal(t, []string{"ibm_resource_group.group"}, resource.Instances[0].Dependencies)
+	}
+}
+
+func TestParseState_version3(t *testing.T) {
+	data := `{"version":3,"terraform_version":"0.11.14","serial":2,"lineage":"a1b2","modules":[{"path":["root"],"outputs":{"name":{"sensitive":false,"type":"string","value":"gics"}},"resources":{"ibm_resource_group.group":{"type":"ibm_resource_group","provider":"provider.ibm","primary":{"id":"1234","attributes":{"name":"gics"}}}}},{"path":["root","network"],"resources":{"ibm_is_subnet.app.0":{"type":"ibm_is_subnet","primary":{"id":"0717-456","attributes":{}}},"ibm_is_subnet.app.1":{"type":"ibm_is_subnet","primary":{"id":"0717-789","attributes":{}}}}}]}`
+
+	state, err := ParseState([]byte(data))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, 3, state.Version)
+	assert.Equal(t, "gics", state.Outputs["name"].Value)
+	if assert.Len(t, state.Resources, 2) {
+		assert.Equal(t, "ibm_resource_group.group", state.Resources[0].Address)
+		assert.Equal(t, "module.network.ibm_is_subnet.app", state.Resources[1].Address)
+	}
+	instance, ok := state.Instance("module.network.ibm_is_subnet.app[1]")
+	if assert.True(t, ok) {
+		assert.Equal(t, "0717-789", instance.Attributes["id"])
+	}
+	instance, ok = state.Instance("ibm_resource_group.group")
+	if assert.True(t, ok) {
+		assert.Equal(t, map[string]interface{}{"id": "1234", "name": "gics"}, instance.Attributes)
+	}
+
+	_, err = ParseState([]byte("not a state"))
+	assert.Error(t, err)
+}
@@ -1,5 +1,10 @@
 package schematics
 
+import (
+	"fmt"
+	"strings"
+)
+
 // WorkspaceStatus is the status of a Schematics workspace
 type WorkspaceStatus string
 
@@ -7,36 +12,205 @@ const (
 	// WorkspaceStatusNew is the status when the Workspace being created
 	WorkspaceStatusNew = WorkspaceStatus("NEW")
 
-	// WorkspaceStatusInactive is the status when it's alredy created waiting to do the planning
+	// WorkspaceStatusDraft is the status when the workspace is created without
+	// a reference to a Git repository or uploaded code
+	WorkspaceStatusDraft = WorkspaceStatus("DRAFT")
+
+	// WorkspaceStatusConnecting is the status when Schematics is connecting to
+	// the template in the source repository to download it
+	WorkspaceStatusConnecting = WorkspaceStatus("CONNECTING")
+
+	// WorkspaceStatusScanning is the status when the template was downloaded and
+	// it's being scanned
+	WorkspaceStatusScanning = WorkspaceStatus("SCANNING")
+
+	// WorkspaceStatusInactive is the status when it's alredy created waiting to do
+	// the planning, or when all the resources were destroyed
 	WorkspaceStatusInactive = WorkspaceStatus("INACTIVE")
 
+	// WorkspaceStatusInProgress is the status when the workspace is executing an
+	// action, such as plan, apply or destroy
+	WorkspaceStatusInProgress = WorkspaceStatus("INPROGRESS")
+
+	// WorkspaceStatusActive is the status when the Terraform code was successfully
+	// applied
+	WorkspaceStatusActive = WorkspaceStatus("ACTIVE")
+
+	// WorkspaceStatusFailed is the status when the execution of an action failed
+	WorkspaceStatusFailed = WorkspaceStatus("FAILED")
+
+	// WorkspaceStatusStopped is the status when a plan, apply or destroy action
+	// was cancelled
+	WorkspaceStatusStopped = WorkspaceStatus("STOPPED")
+
+	// WorkspaceStatusTemplateError is the status when the template contains
+	// errors and cannot be processed
+	WorkspaceStatusTemplateError = WorkspaceStatus("TEMPLATE_ERROR")
+
+	// WorkspaceStatusDestroyed is the status when the workspace resources were destroyed and the workspace deleted
+	WorkspaceStatusDestroyed = WorkspaceStatus("DESTROYED")
+
+	// WorkspaceStatusDeleted is the status when the workspace was deleted
+	WorkspaceStatusDeleted = WorkspaceStatus("DELETED")
+)
+
+const (
 	// WorkspaceStatusPlaning is the status when the workspace is doing the planning
+	//
+	// Deprecated: The API reports WorkspaceStatusInProgress while planning
 	WorkspaceStatusPlaning = WorkspaceStatus("PLANING")
 
 	// WorkspaceStatusPlanned is the status when the workspace planing is completed
+	//
+	// Deprecated: The API does not report a status for a completed plan
 	WorkspaceStatusPlanned = WorkspaceStatus("PLANNED")
 
 	// WorkspaceStatusApplying is the status when the workspace is applying the changes
+	//
+	// Deprecated: The API reports WorkspaceStatusInProgress while applying
 	WorkspaceStatusApplying = WorkspaceStatus("APPLYING")
+)
 
-	// WorkspaceStatusDestroyed is the status when the workspace resources were destroyed and the workspace deleted
-	WorkspaceStatusDestroyed = WorkspaceStatus("DESTROYED")
+// WorkspaceAction is an action that can be executed on a Schematics workspace
+type WorkspaceAction string
 
-	// WorkspaceStatusDeleted is the status when the workspace was deleted
-	WorkspaceStatusDeleted = WorkspaceStatus("DELETED")
+const (
+	// WorkspaceActionCreate creates the workspace
+	WorkspaceActionCreate = WorkspaceAction("create")
+	// WorkspaceActionUpdate updates the workspace settings or variables
+	WorkspaceActionUpdate = WorkspaceAction("update")
+	// WorkspaceActionUpload uploads the code to the workspace
+	WorkspaceActionUpload = WorkspaceAction("upload")
+	// WorkspaceActionPlan generates the Terraform plan
+	WorkspaceActionPlan = WorkspaceAction("plan")
+	// WorkspaceActionApply applies the Terraform code
+	WorkspaceActionApply = WorkspaceAction("apply")
+	// WorkspaceActionRefresh refreshes the Terraform state
+	WorkspaceActionRefresh = WorkspaceAction("refresh")
+	// WorkspaceActionDestroy destroys the resources created by the workspace
+	WorkspaceActionDestroy = WorkspaceAction("destroy")
+	// WorkspaceActionDelete deletes the workspace
+	WorkspaceActionDelete = WorkspaceAction("delete")
 )
 
-// Status is the status of a Schematics workspace
-// type Status int
+// workspaceTransitions are the actions allowed on each workspace status
+var workspaceTransitions = map[WorkspaceStatus][]WorkspaceAction{
+	WorkspaceStatusNew:           {WorkspaceActionCreate},
+	WorkspaceStatusDraft:         {WorkspaceActionUpdate, WorkspaceActionUpload, WorkspaceActionDelete},
+	WorkspaceStatusConnecting:    {},
+	WorkspaceStatusScanning:      {},
+	WorkspaceStatusInactive:      {WorkspaceActionUpdate, WorkspaceActionUpload, WorkspaceActionPlan, WorkspaceActionApply, WorkspaceActionRefresh, WorkspaceActionDestroy, WorkspaceActionDelete},
+	WorkspaceStatusInProgress:    {},
+	WorkspaceStatusActive:        {WorkspaceActionUpdate, WorkspaceActionUpload, WorkspaceActionPlan, WorkspaceActionApply, WorkspaceActionRefresh, WorkspaceActionDestroy, WorkspaceActionDelete},
+	WorkspaceStatusFailed:        {WorkspaceActionUpdate, WorkspaceActionUpload, WorkspaceActionPlan, WorkspaceActionApply, WorkspaceActionRefresh, WorkspaceActionDestroy, WorkspaceActionDelete},
+	WorkspaceStatusStopped:       {WorkspaceActionUpdate, WorkspaceActionUpload, WorkspaceActionPlan, WorkspaceActionApply, WorkspaceActionRefresh, WorkspaceActionDestroy, WorkspaceActionDelete},
+	WorkspaceStatusTemplateError: {WorkspaceActionUpdate, WorkspaceActionUpload, WorkspaceActionDelete},
+	WorkspaceStatusDestroyed:     {},
+	WorkspaceStatusDeleted:       {},
+}
+
+// ParseWorkspaceStatus returns the WorkspaceStatus of the given status returned
+// by the API. The API may return it in different case or with spaces
+// (i.e. "In Progress", "Template Error")
+func ParseWorkspaceStatus(status string) WorkspaceStatus {
+	s := strings.ToUpper(strings.TrimSpace(status))
+	switch s {
+	case "IN PROGRESS", "IN_PROGRESS":
+		return WorkspaceStatusInProgress
+	case "TEMPLATE ERROR", "TEMPLATEERROR":
+		return WorkspaceStatusTemplateError
+	}
+	return WorkspaceStatus(s)
+}
+
+// IsValid returns true if the status is a known workspace status
+func (s WorkspaceStatus) IsValid() bool {
+	_, ok := workspaceTransitions[s]
+	return ok
+}
+
+// IsBusy returns true if the workspace is executing an action or processing
+// the template, so no other action can be executed until it's done
+func (s WorkspaceStatus) IsBusy() bool {
+	switch s {
+	case WorkspaceStatusConnecting, WorkspaceStatusScanning, WorkspaceStatusInProgress:
+		return true
+	}
+	return false
+}
+
+// IsTerminal returns true if the workspace is not transitioning to another
+// status, the opposite of IsBusy for a known status
+func (s WorkspaceStatus) IsTerminal() bool {
+	return s.IsValid() && !s.IsBusy()
+}
+
+// IsFailed returns true if the last action or the template processing failed
+func (s WorkspaceStatus) IsFailed() bool {
+	return s == WorkspaceStatusFailed || s == WorkspaceStatusTemplateError
+}
+
+// Can returns true if the given action can be executed on a workspace with
+// this status. An unknown status, like a new status added to the API, allows
+// any action but create, the API rejects the action if it's not allowed
+func (s WorkspaceStatus) Can(action WorkspaceAction) bool {
+	if !s.IsValid() {
+		return action != WorkspaceActionCreate
+	}
+	for _, a := range workspaceTransitions[s] {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// CanPlan returns true if a plan can be generated in this status
+func (s WorkspaceStatus) CanPlan() bool {
+	return s.Can(WorkspaceActionPlan)
+}
+
+// CanApply returns true if the code can be applied in this status
+func (s WorkspaceStatus) CanApply() bool {
+	return s.Can(WorkspaceActionApply)
+}
+
+// CanDestroy returns true if the resources can be destroyed in this status
+func (s WorkspaceStatus) CanDestroy() bool {
+	return s.Can(WorkspaceActionDestroy)
+}
+
+// CanDelete returns true if the workspace can be deleted in this status
+func (s WorkspaceStatus) CanDelete() bool {
+	return s.Can(WorkspaceActionDelete)
+}
 
-// const (
-// 	StatusNew Status = iota
-// 	StatusInactive
-// )
+// validateAction returns an error if the given action can't be executed on the
+// workspace because of its status, or because it's locked or frozen
+func (w *Workspace) validateAction(action WorkspaceAction) error {
+	if w.Locked {
+		lockedBy := ""
+		if len(w.LockedBy) != 0 {
+			lockedBy = " by " + w.LockedBy
+		}
+		return fmt.Errorf("cannot %s the workspace %q, it's locked%s", action, w.Name, lockedBy)
+	}
+	if w.Frozen && action != WorkspaceActionUpdate {
+		return fmt.Errorf("cannot %s the workspace %q, it's frozen", action, w.Name)
+	}
+	if !w.Status.Can(action) {
+		msg := fmt.Sprintf("cannot %s the workspace %q in status %s", action, w.Name, w.Status)
+		if w.Status.IsBusy() {
+			msg += ", wait until the current action is done"
+		}
+		if len(w.StatusMessage) != 0 {
+			msg += ". " + w.StatusMessage
+		}
+		return fmt.Errorf("%s", msg)
+	}
+	if !w.Status.IsValid() {
+		w.logPrintf("unknown status %s of the workspace %q, trying to %s it anyway", w.Status, w.Name, action)
+	}
 
-// func (s Status) String() string {
-// 	return [...]string{
-// 		statusNew,
-// 		statusInactive,
-// 	}[s]
-// }
+	return nil
+}
@@ -0,0 +1,119 @@
+package schematics
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWorkspaceStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		want   WorkspaceStatus
+	}{
+		{"ACTIVE", WorkspaceStatusActive},
+		{"draft", WorkspaceStatusDraft},
+		{"In Progress", WorkspaceStatusInProgress},
+		{"INPROGRESS", WorkspaceStatusInProgress},
+		{"Template Error", WorkspaceStatusTemplateError},
+		{"TEMPLATE_ERROR", WorkspaceStatusTemplateError},
+		{"UNKNOWN", WorkspaceStatus("UNKNOWN")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseWorkspaceStatus(tt.status))
+		})
+	}
+}
+
+func TestWorkspaceStatus_helpers(t *testing.T) {
+	tests := []struct {
+		status     WorkspaceStatus
+		isBusy     bool
+		isTerminal bool
+		canPlan    bool
+		canApply   bool
+		canDelete  bool
+	}{
+		{WorkspaceStatusNew, false, true, false, false, false},
+		{WorkspaceStatusDraft, false, true, false, false, true},
+		{WorkspaceStatusConnecting, true, false, false, false, false},
+		{WorkspaceStatusScanning, true, false, false, false, false},
+		{WorkspaceStatusInactive, false, true, true, true, true},
+		{WorkspaceStatusInProgress, true, false, false, false, false},
+		{WorkspaceStatusActive, false, true, true, true, true},
+		{WorkspaceStatusFailed, false, true, true, true, true},
+		{WorkspaceStatusStopped, false, true, true, true, true},
+		{WorkspaceStatusTemplateError, false, true, false, false, true},
+		{WorkspaceStatusDeleted, false, true, false, false, false},
+		{WorkspaceStatus("UNKNOWN"), false, false, true, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			assert.Equal(t, tt.isBusy, tt.status.IsBusy(), "IsBusy()")
+			assert.Equal(t, tt.isTerminal, tt.status.IsTerminal(), "IsTerminal()")
+			assert.Equal(t, tt.canPlan, tt.status.CanPlan(), "CanPlan()")
+			assert.Equal(t, tt.canApply, tt.status.CanApply(), "CanApply()")
+			assert.Equal(t, tt.canDelete, tt.status.CanDelete(), "CanDelete()")
+		})
+	}
+}
+
+func TestWorkspace_validateAction(t *testing.T) {
+	tests := []struct {
+		name      string
+		workspace *Workspace
+		action    WorkspaceAction
+		wantErr   string
+	}{
+		{"apply active", &Workspace{Name: "w", Status: WorkspaceStatusActive}, WorkspaceActionApply, ""},
+		{"apply locked", &Workspace{Name: "w", Status: WorkspaceStatusActive, Locked: true, LockedBy: "johandry@gmail.com"}, WorkspaceActionApply, `cannot apply the workspace "w", it's locked by johandry@gmail.com`},
+		{"apply frozen", &Workspace{Name: "w", Status: WorkspaceStatusActive, Frozen: true}, WorkspaceActionApply, `cannot apply the workspace "w", it's frozen`},
+		{"update frozen", &Workspace{Name: "w", Status: WorkspaceStatusActive, Frozen: true}, WorkspaceActionUpdate, ""},
+		{"apply in progress", &Workspace{Name: "w", Status: WorkspaceStatusInProgress}, WorkspaceActionApply, `cannot apply the workspace "w" in status INPROGRESS, wait until the current action is done`},
+		{"delete unknown status", &Workspace{Name: "w", Status: WorkspaceStatus("ARCHIVED")}, WorkspaceActionDelete, ""},
+		{"plan template error", &Workspace{Name: "w", Status: WorkspaceStatusTemplateError, StatusMessage: "syntax error"}, WorkspaceActionPlan, `cannot plan the workspace "w" in status TEMPLATE_ERROR. syntax error`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.workspace.validateAction(tt.action)
+			if len(tt.wantErr) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestWorkspace_Apply_locked(t *testing.T) {
+	workspaceName := "locked"
+	workspaceID := fmt.Sprintf("%s-8d2ef3a4-5b6c-7d", workspaceName)
+
+	httpmock.RegisterResponder("GET", "https://schematics.cloud.ibm.com/v1/workspaces/"+workspaceID,
+		func(req *http.Request) (*http.Response, error) {
+			fixture := fmt.Sprintf(`{"id":"%s","name":"%s","status":"ACTIVE","workspace_status":{"frozen":false,"locked":true,"locked_by":"johandry@gmail.com"}}`, workspaceID, workspaceName)
+			resp := httpmock.NewStringResponse(200, fixture)
+			resp.Header.Add("Content-Type", "application/json; charset=utf-8")
+			return resp, nil
+		},
+	)
+	applyCalls := 0
+	httpmock.RegisterResponder("PUT", fmt.Sprintf("https://schematics.cloud.ibm.com/v1/workspaces/%s/apply", workspaceID),
+		func(req *http.Request) (*http.Response, error) {
+			applyCalls++
+			return httpmock.NewStringResponse(202, `{}`), nil
+		},
+	)
+
+	w := New(workspaceName, "", nil)
+	w.ID = workspaceID
+
+	_, err := w.Apply()
+	assert.EqualError(t, err, `cannot apply the workspace "locked", it's locked by johandry@gmail.com`)
+	assert.Equal(t, 0, applyCalls, "the apply request should not be sent")
+	assert.True(t, w.Locked)
+}
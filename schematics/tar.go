@@ -3,9 +3,18 @@ package schematics
 import (
 	"archive/tar"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"io/fs"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	apiv1 "github.com/johandry/gics/schematics/api/v1"
@@ -15,61 +24,289 @@ const (
 	uploadTarWorkspaceTimeout = 50
 )
 
-// UploadTar upload a compressed (Tar) file/content into the workspace
+// codeHashTagPrefix is the prefix of the workspace tag with the hash of the
+// uploaded code
+const codeHashTagPrefix = "gics-code-sha256:"
+
+// codeCommitTagPrefix is the prefix of the workspace tag with the commit SHA
+// of the uploaded code, when it's loaded from a local Git checkout
+const codeCommitTagPrefix = "gics-code-commit:"
+
+// gzipMagic are the first bytes of a gzip file
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// tarModTime is the modification time of every file in the tar, so the tar of
+// the same code is always the same
+var tarModTime = time.Unix(0, 0).UTC()
+
+// defaultFileMode is the mode of the code files, unless other mode is set with
+// AddFile() or read from the directory
+const defaultFileMode os.FileMode = 0644
+
+// UploadOptions are the parameters to modify the behavior of
+// UploadTarWithOptions
+type UploadOptions struct {
+	// Gzip compresses the tar before uploading it
+	Gzip bool
+}
+
+// UploadError is the error returned when the code upload fails
+type UploadError struct {
+	WorkspaceID string
+	TemplateID  string
+	// StatusCode is the HTTP status code returned by the API, it's 0 if the
+	// request failed before getting a response
+	StatusCode int
+	Err        error
+}
+
+func (e *UploadError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("failed to upload the code to the workspace %s (template %s), status code %d. %s", e.WorkspaceID, e.TemplateID, e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("failed to upload the code to the workspace %s (template %s). %s", e.WorkspaceID, e.TemplateID, e.Err)
+}
+
+// Unwrap returns the cause of the upload error
+func (e *UploadError) Unwrap() error {
+	return e.Err
+}
+
+// UploadTar upload a Tar file/content into the workspace. If the body is nil,
+// the pre-built archive in TarCode is uploaded
 func (w *Workspace) UploadTar(body io.Reader) error {
-	// Delete Timeout
+	return w.UploadTarWithOptions(body, nil)
+}
+
+// UploadTarWithOptions upload a Tar file/content into the workspace, it's
+// compressed with gzip if requested in the options. The body is streamed in a
+// multipart form, in the field `file`, so it's not buffered in memory. If the
+// body is nil, the pre-built archive in TarCode is uploaded. The loaded code is
+// linted and scanned for secrets before the upload, it's not uploaded if Lint()
+// reports errors or a secret is found. The upload errors are returned as
+// *UploadError
+func (w *Workspace) UploadTarWithOptions(body io.Reader, opt *UploadOptions) error {
+	if opt == nil {
+		opt = &UploadOptions{}
+	}
+
+	filename, compress := "code.tar", opt.Gzip
+	if body == nil {
+		if len(w.TarCode) == 0 {
+			return fmt.Errorf("there is no code to upload to the workspace %q", w.Name)
+		}
+		body = bytes.NewReader(w.TarCode)
+		// the pre-built archive may be compressed already
+		if bytes.HasPrefix(w.TarCode, gzipMagic) {
+			compress = false
+			filename = "code.tar.gz"
+		}
+	}
+	if compress {
+		filename = "code.tar.gz"
+	}
+
+	if len(w.tfCodeFiles) != 0 {
+		if err := w.lint(); err != nil {
+			return err
+		}
+	}
+	if err := w.checkCodeSecrets(); err != nil {
+		return err
+	}
+
+	// UploadTar Timeout
 	ctx, cancelFunc := context.WithTimeout(context.Background(), uploadTarWorkspaceTimeout*time.Second)
 	defer cancelFunc()
 
+	if err := w.checkAction(ctx, WorkspaceActionUpload); err != nil {
+		return err
+	}
+	if len(w.templateID) == 0 {
+		return fmt.Errorf("the workspace %q has no template to upload the code", w.Name)
+	}
+
+	uploadErr := func(code int, err error) error {
+		return &UploadError{WorkspaceID: w.ID, TemplateID: w.templateID, StatusCode: code, Err: err}
+	}
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeMultipartTar(mw, filename, body, compress))
+	}()
+	defer pr.Close()
+
 	params := &apiv1.UploadTemplateTarParams{}
-	resp, err := w.service.clientWithResponses.UploadTemplateTarWithBodyWithResponse(ctx, w.ID, templateIDDefault, params, "multipart/form-data", body)
+	resp, err := w.api().clientWithResponses.UploadTemplateTarWithBodyWithResponse(ctx, w.ID, w.templateID, params, mw.FormDataContentType(), pr)
 	if err != nil {
-		return err
+		return uploadErr(0, err)
 	}
 	if code := resp.StatusCode(); code != 200 {
-		return getAPIError("failed to upload the compressed code", resp.Body)
+		return uploadErr(code, getAPIError("failed to upload the compressed code", resp.Body))
+	}
+	response := resp.JSON200 // TemplateRepoTarUploadResponse
+	if response == nil || !boolValue(response.HasReceivedFile) {
+		return uploadErr(resp.StatusCode(), fmt.Errorf("the file was not received"))
 	}
-	response := resp.JSON200 // WorkspaceDeleteResponse
 
-	fmt.Printf("[DEBUG] code uploaded. Response: %+v\n", response)
+	w.logPrintf("code uploaded to the template %s", stringValue(response.Id))
 
-	if !*response.HasReceivedFile {
-		return fmt.Errorf("failed to upload the code")
+	return nil
+}
+
+// writeMultipartTar writes the tar in the `file` field of the multipart form,
+// compressed with gzip if requested
+func writeMultipartTar(mw *multipart.Writer, filename string, body io.Reader, compress bool) error {
+	part, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		return err
 	}
 
-	// TODO: Complete the UploadTar func
+	if compress {
+		gw := gzip.NewWriter(part)
+		if _, err := io.Copy(gw, body); err != nil {
+			return err
+		}
+		if err := gw.Close(); err != nil {
+			return err
+		}
+	} else if _, err := io.Copy(part, body); err != nil {
+		return err
+	}
 
-	return nil
+	return mw.Close()
 }
 
+// tarMemFiles tar the code files in memory
 func (w *Workspace) tarMemFiles() (io.Reader, error) {
 	var buf bytes.Buffer
-	tw := tar.NewWriter(&buf)
+	if err := w.writeTar(&buf); err != nil {
+		return nil, err
+	}
 
-	for name, body := range w.tfCodeFiles {
+	return &buf, nil
+}
+
+// writeTar writes the tar of the code files. The tar is reproducible, the same
+// files and modes produce the same bytes: the entries are sorted by name and
+// the headers have a fixed modification time and ownership
+func (w *Workspace) writeTar(out io.Writer) error {
+	tw := tar.NewWriter(out)
+
+	for _, name := range sortedKeys(w.tfCodeFiles) {
+		body := w.tfCodeFiles[name]
 		hdr := &tar.Header{
-			Name: name,
-			Mode: 0600,
-			Size: int64(len(body)),
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     int64(w.fileMode(name)),
+			Size:     int64(len(body)),
+			ModTime:  tarModTime,
+			// owned by root, regardless of the user creating the tar
+			Uid: 0,
+			Gid: 0,
 		}
 		if err := tw.WriteHeader(hdr); err != nil {
-			return nil, err
+			return err
 		}
-		if _, err := tw.Write([]byte(body)); err != nil {
-			return nil, err
+		if _, err := io.WriteString(tw, body); err != nil {
+			return err
 		}
 	}
-	if err := tw.Close(); err != nil {
-		return nil, err
+
+	return tw.Close()
+}
+
+// fileMode returns the mode of the given code file
+func (w *Workspace) fileMode(name string) os.FileMode {
+	if mode, ok := w.tfFileModes[name]; ok {
+		return mode
+	}
+	return defaultFileMode
+}
+
+// codeHash returns the SHA256 of the loaded code files, it's the same for the
+// same files and content regardless of the order they were loaded. It's stored
+// in the workspace tags to skip the upload of unchanged code
+func (w *Workspace) codeHash() string {
+	names := make([]string, 0, len(w.tfCodeFiles))
+	for name := range w.tfCodeFiles {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	return &buf, nil
+	h := sha256.New()
+	for _, name := range names {
+		io.WriteString(h, name)
+		h.Write([]byte{0})
+		io.WriteString(h, w.tfCodeFiles[name])
+		h.Write([]byte{0})
+		// The default mode is not hashed to keep the hash of the code uploaded
+		// before the modes were supported
+		if mode, ok := w.tfFileModes[name]; ok {
+			fmt.Fprintf(h, "%o", mode)
+			h.Write([]byte{0})
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
 }
 
-func (w *Workspace) tarCode() (io.Reader, error) {
-	return nil, nil
+// readDirFiles returns the content of every regular file in the given
+// directory and subdirectories, indexed by the path relative to the directory,
+// and the mode of the files with a mode different to the default
+func readDirFiles(dir string) (map[string]string, map[string]os.FileMode, error) {
+	return readFSFiles(os.DirFS(dir), ".")
 }
 
-func (w *Workspace) tarDir(dir string) (io.Reader, error) {
-	return nil, nil
+// readFSFiles returns the content of every regular file in the given root
+// directory of the file system, indexed by the path relative to the root, and
+// the mode of the files with a mode different to the default
+func readFSFiles(fsys fs.FS, root string) (map[string]string, map[string]os.FileMode, error) {
+	files := map[string]string{}
+	modes := map[string]os.FileMode{}
+
+	err := fs.WalkDir(fsys, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(path, root+"/")
+		if root == "." {
+			name = path
+		}
+		files[name] = string(content)
+		if info.Mode().Perm()&0111 != 0 {
+			modes[name] = 0755
+		}
+		return nil
+	})
+
+	return files, modes, err
+}
+
+// codeFilePath validates and cleans the path of a code file, it has to be
+// relative to the root of the code and cannot be outside of it
+func codeFilePath(path string) (string, error) {
+	name := filepath.ToSlash(filepath.Clean(filepath.FromSlash(path)))
+	if len(path) == 0 || name == "." {
+		return "", fmt.Errorf("invalid file path, it cannot be an empty string")
+	}
+	if filepath.IsAbs(path) || strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("invalid file path %q, it has to be relative to the root of the code", path)
+	}
+	if name == ".." || strings.HasPrefix(name, "../") {
+		return "", fmt.Errorf("invalid file path %q, it cannot be outside of the root of the code", path)
+	}
+	return name, nil
 }
@@ -0,0 +1,255 @@
+package schematics
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// untar returns the content and mode of the files in the given tar
+func untar(t *testing.T, r io.Reader) (map[string]string, map[string]int64) {
+	files := map[string]string{}
+	modes := map[string]int64{}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			return nil, nil
+		}
+		content, _ := ioutil.ReadAll(tr)
+		files[hdr.Name] = string(content)
+		modes[hdr.Name] = hdr.Mode
+	}
+
+	return files, modes
+}
+
+func TestWorkspace_AddFile(t *testing.T) {
+	w := New("files", "", nil)
+
+	err := w.LoadFiles(map[string][]byte{
+		"main.tf":                    []byte(`module "network" { source = "./modules/network" }`),
+		"./modules/network/main.tf":  []byte(`resource "ibm_is_vpc" "main" {}`),
+		"modules/network/outputs.tf": []byte(`output "id" { value = ibm_is_vpc.main.id }`),
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	hash := w.codeHash()
+
+	assert.NoError(t, w.AddFile("scripts/init.sh", []byte("#!/bin/sh\necho init\n"), 0755))
+	assert.NoError(t, w.AddFile("modules/network/outputs.tf", []byte(`output "vpc_id" { value = ibm_is_vpc.main.id }`), 0))
+	assert.NotEqual(t, hash, w.codeHash(), "the hash should change when a file is added")
+
+	files, modes := untar(t, w.tfBuf)
+	assert.Equal(t, map[string]string{
+		"main.tf":                    `module "network" { source = "./modules/network" }`,
+		"modules/network/main.tf":    `resource "ibm_is_vpc" "main" {}`,
+		"modules/network/outputs.tf": `output "vpc_id" { value = ibm_is_vpc.main.id }`,
+		"scripts/init.sh":            "#!/bin/sh\necho init\n",
+	}, files)
+	assert.Equal(t, map[string]int64{
+		"main.tf":                    0644,
+		"modules/network/main.tf":    0644,
+		"modules/network/outputs.tf": 0644,
+		"scripts/init.sh":            0755,
+	}, modes)
+
+	hash = w.codeHash()
+	w.AddFile("scripts/init.sh", []byte("#!/bin/sh\necho init\n"), 0644)
+	assert.NotEqual(t, hash, w.codeHash(), "the hash should change when the mode changes")
+
+	for _, path := range []string{"", ".", "/etc/passwd", "../main.tf", "modules/../../main.tf"} {
+		assert.Error(t, w.AddFile(path, []byte("x"), 0), "AddFile(%q) should fail", path)
+	}
+}
+
+func TestWorkspace_LoadDir_modes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gics-load-dir")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(dir+"/main.tf", []byte(`variable "prefix" {}`), 0600)
+	ioutil.WriteFile(dir+"/init.sh", []byte("#!/bin/sh\n"), 0700)
+
+	w := New("dir", "", nil)
+	if !assert.NoError(t, w.LoadDir(dir)) {
+		return
+	}
+
+	_, modes := untar(t, w.tfBuf)
+	assert.Equal(t, map[string]int64{"main.tf": 0644, "init.sh": 0755}, modes)
+}
+
+func TestWorkspace_LoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"templates/vpc/main.tf":           {Data: []byte(`resource "ibm_is_vpc" "main" {}`)},
+		"templates/vpc/scripts/init.sh":   {Data: []byte("#!/bin/sh\n"), Mode: 0755},
+		"templates/vpc/modules/a/main.tf": {Data: []byte(`variable "name" {}`)},
+		"templates/other/main.tf":         {Data: []byte(`resource "ibm_resource_group" "group" {}`)},
+	}
+
+	w := New("fs", "", nil)
+	if !assert.NoError(t, w.LoadFS(fsys, "templates/vpc")) {
+		return
+	}
+
+	files, modes := untar(t, w.tfBuf)
+	assert.Equal(t, map[string]string{
+		"main.tf":           `resource "ibm_is_vpc" "main" {}`,
+		"scripts/init.sh":   "#!/bin/sh\n",
+		"modules/a/main.tf": `variable "name" {}`,
+	}, files)
+	assert.Equal(t, int64(0755), modes["scripts/init.sh"])
+
+	assert.NoError(t, w.LoadFS(fsys, "."))
+	assert.Len(t, w.tfCodeFiles, 4)
+
+	assert.Error(t, w.LoadFS(fsys, "templates/none"))
+	assert.Error(t, w.LoadFS(fsys, "/templates"))
+}
+
+func TestWorkspace_tarMemFiles_reproducible(t *testing.T) {
+	files := map[string][]byte{}
+	for _, name := range []string{"main.tf", "variables.tf", "outputs.tf", "modules/a/main.tf", "modules/b/main.tf", "scripts/init.sh"} {
+		files[name] = []byte("# " + name)
+	}
+
+	var tars [][]byte
+	for i := 0; i < 5; i++ {
+		w := New("tar", "", nil)
+		if !assert.NoError(t, w.LoadFiles(files)) {
+			return
+		}
+		data, _ := ioutil.ReadAll(w.tfBuf)
+		tars = append(tars, data)
+	}
+	for _, data := range tars[1:] {
+		assert.Equal(t, tars[0], data, "the tar of the same files should be the same")
+	}
+
+	var names []string
+	tr := tar.NewReader(bytes.NewReader(tars[0]))
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, hdr.Name)
+		assert.Equal(t, int64(0), hdr.ModTime.Unix())
+		assert.Equal(t, 0, hdr.Uid)
+		assert.Equal(t, 0, hdr.Gid)
+	}
+	assert.Equal(t, []string{"main.tf", "modules/a/main.tf", "modules/b/main.tf", "outputs.tf", "scripts/init.sh", "variables.tf"}, names)
+}
+
+func TestWorkspace_UploadTarWithOptions(t *testing.T) {
+	workspaceName := "upload"
+	workspaceID := fmt.Sprintf("%s-8e9f0a1b-2c3d-4e", workspaceName)
+	templateID := "iac-b8c9d0e1-2f3a-4b"
+
+	httpmock.RegisterResponder("GET", "https://schematics.cloud.ibm.com/v1/workspaces/"+workspaceID,
+		jsonResponder(200, fmt.Sprintf(`{"id":"%s","name":"%s","status":"INACTIVE","template_data":[{"id":"%s","folder":".","type":"terraform_v0.13"}]}`, workspaceID, workspaceName, templateID)),
+	)
+
+	var gotContentType, gotFilename string
+	var gotFiles map[string]string
+	status := 200
+	httpmock.RegisterResponder("PUT", fmt.Sprintf("https://schematics.cloud.ibm.com/v1/workspaces/%s/templates/%s/template_repo_upload", workspaceID, templateID),
+		func(req *http.Request) (*http.Response, error) {
+			if status != 200 {
+				return jsonResponder(status, `{"StatusCode":500,"messageid":"M1078","message":"Failed to upload the tar file"}`)(req)
+			}
+			gotContentType = req.Header.Get("Content-Type")
+			file, hdr, err := req.FormFile("file")
+			if err != nil {
+				return nil, err
+			}
+			gotFilename = hdr.Filename
+			var r io.Reader = file
+			if strings.HasSuffix(hdr.Filename, ".gz") {
+				if r, err = gzip.NewReader(file); err != nil {
+					return nil, err
+				}
+			}
+			gotFiles, _ = untar(t, r)
+			return jsonResponder(200, fmt.Sprintf(`{"file_value":"%s","has_received_file":true,"id":"%s"}`, hdr.Filename, templateID))(req)
+		},
+	)
+
+	// The template ID is unknown, it's taken from the workspace
+	w := New(workspaceName, "", nil)
+	w.ID = workspaceID
+	w.LoadCode(`variable "prefix" {}`)
+
+	for _, compress := range []bool{false, true} {
+		body, _ := w.tarMemFiles()
+		if !assert.NoError(t, w.UploadTarWithOptions(body, &UploadOptions{Gzip: compress})) {
+			return
+		}
+		assert.True(t, strings.HasPrefix(gotContentType, "multipart/form-data; boundary="), "the content type should have the multipart boundary, got %q", gotContentType)
+		assert.Equal(t, map[string]string{"main.tf": `variable "prefix" {}`}, gotFiles)
+		if compress {
+			assert.Equal(t, "code.tar.gz", gotFilename)
+		} else {
+			assert.Equal(t, "code.tar", gotFilename)
+		}
+	}
+
+	// The pre-built archive in TarCode is uploaded as is
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	body, _ := w.tarMemFiles()
+	io.Copy(gw, body)
+	gw.Close()
+	w.TarCode = gz.Bytes()
+	if assert.NoError(t, w.UploadTar(nil)) {
+		assert.Equal(t, "code.tar.gz", gotFilename)
+		assert.Equal(t, map[string]string{"main.tf": `variable "prefix" {}`}, gotFiles)
+	}
+	w.TarCode = nil
+	assert.Error(t, w.UploadTar(nil), "UploadTar() should fail without body or TarCode")
+
+	status = 500
+	body, _ = w.tarMemFiles()
+	err := w.UploadTar(body)
+	var uploadErr *UploadError
+	if assert.True(t, errors.As(err, &uploadErr), "UploadTar() should return an UploadError, got %v", err) {
+		assert.Equal(t, 500, uploadErr.StatusCode)
+		assert.Equal(t, workspaceID, uploadErr.WorkspaceID)
+		assert.Equal(t, templateID, uploadErr.TemplateID)
+		assert.Contains(t, uploadErr.Error(), "Failed to upload the tar file")
+	}
+
+	// A workspace without template cannot receive the code
+	noTemplateID := fmt.Sprintf("%s-0f1e2d3c-4b5a-69", workspaceName)
+	httpmock.RegisterResponder("GET", "https://schematics.cloud.ibm.com/v1/workspaces/"+noTemplateID,
+		jsonResponder(200, fmt.Sprintf(`{"id":"%s","name":"%s","status":"INACTIVE"}`, noTemplateID, workspaceName)),
+	)
+	w = New(workspaceName, "", nil)
+	w.ID = noTemplateID
+	w.LoadCode(`variable "prefix" {}`)
+	body, _ = w.tarMemFiles()
+	err = w.UploadTar(body)
+	if assert.Error(t, err, "UploadTar() should fail without template") {
+		assert.Contains(t, err.Error(), "has no template")
+	}
+}
@@ -0,0 +1,154 @@
+package schematics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// redacted replaces the secrets in the traces
+const redacted = "[REDACTED]"
+
+// maxTraceBodySize is the maximum size of a body in the traces, the rest is
+// truncated
+const maxTraceBodySize = 64 * 1024
+
+// secretHeaders are the headers with secrets, in canonical format
+var secretHeaders = map[string]bool{
+	"Authorization":   true,
+	"Refresh_token":   true,
+	"Delegated_token": true,
+	"X-Github-Token":  true,
+	"Cookie":          true,
+	"Set-Cookie":      true,
+}
+
+// traceRequest writes the request to the trace output, with the secrets
+// redacted. A body that cannot be read again, like the streamed code upload,
+// is not traced
+func (c *ICClient) traceRequest(req *http.Request, attempt int) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "> %s %s", req.Method, req.URL)
+	if attempt > 0 {
+		fmt.Fprintf(&buf, " (retry %d)", attempt)
+	}
+	buf.WriteString("\n")
+	traceHeaders(&buf, ">", req.Header)
+
+	switch {
+	case req.Body == nil || req.Body == http.NoBody:
+	case req.GetBody == nil:
+		buf.WriteString(">\n> [streamed body, not traced]\n")
+	default:
+		if body, err := req.GetBody(); err == nil {
+			data, _ := ioutil.ReadAll(body)
+			body.Close()
+			traceBody(&buf, ">", data)
+		}
+	}
+
+	c.trace.Write(buf.Bytes())
+}
+
+// traceResponse writes the response, or the error, to the trace output with
+// the secrets redacted. The response body is read and replaced, so it can be
+// read again by the client
+func (c *ICClient) traceResponse(resp *http.Response, err error, elapsed time.Duration) {
+	var buf bytes.Buffer
+	if err != nil {
+		fmt.Fprintf(&buf, "< ERROR %s (%s)\n\n", err, elapsed.Round(time.Millisecond))
+		c.trace.Write(buf.Bytes())
+		return
+	}
+
+	fmt.Fprintf(&buf, "< %s (%s)\n", resp.Status, elapsed.Round(time.Millisecond))
+	traceHeaders(&buf, "<", resp.Header)
+	if resp.Body != nil {
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+		traceBody(&buf, "<", data)
+	}
+	buf.WriteString("\n")
+
+	c.trace.Write(buf.Bytes())
+}
+
+func traceHeaders(w io.Writer, prefix string, header http.Header) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := strings.Join(header[name], ", ")
+		if secretHeaders[http.CanonicalHeaderKey(name)] {
+			value = redacted
+		}
+		fmt.Fprintf(w, "%s %s: %s\n", prefix, name, value)
+	}
+}
+
+func traceBody(w io.Writer, prefix string, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	body := string(redactJSON(data))
+	if len(body) > maxTraceBodySize {
+		body = body[:maxTraceBodySize] + fmt.Sprintf("... [%d bytes truncated]", len(body)-maxTraceBodySize)
+	}
+	fmt.Fprintf(w, "%s\n%s %s\n", prefix, prefix, body)
+}
+
+// redactJSON returns the JSON document with the secrets redacted: the tokens,
+// API keys and passwords, and the values of the secure variables. A document
+// that is not JSON is returned as it is
+func redactJSON(data []byte) []byte {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return data
+	}
+	redactValue(doc)
+	redactedData, err := json.Marshal(doc)
+	if err != nil {
+		return data
+	}
+	return redactedData
+}
+
+func redactValue(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if secure, _ := v["secure"].(bool); secure {
+			if _, ok := v["value"]; ok {
+				v["value"] = redacted
+			}
+		}
+		for key, value := range v {
+			if secretKey(key) {
+				if _, ok := value.(string); ok {
+					v[key] = redacted
+				}
+				continue
+			}
+			redactValue(value)
+		}
+	case []interface{}:
+		for _, value := range v {
+			redactValue(value)
+		}
+	}
+}
+
+// secretKey returns true if the JSON key holds a secret
+func secretKey(key string) bool {
+	key = strings.ToLower(key)
+	return key == "token" || strings.HasSuffix(key, "_token") || key == "apikey" || key == "api_key" || key == "password"
+}
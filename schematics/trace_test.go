@@ -0,0 +1,61 @@
+package schematics
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/IBM/go-sdk-core/core"
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestICClient_Do_trace(t *testing.T) {
+	url := "https://trace.schematics.cloud.ibm.com/v1/workspaces"
+	httpmock.RegisterResponder("POST", url, jsonResponder(201, `{"id":"trace-1a2b3c","refresh_token":"response-refresh-token"}`))
+
+	var trace bytes.Buffer
+	s := NewService(&ServiceOptions{
+		Authenticator: &core.BearerTokenAuthenticator{BearerToken: "access-token"},
+		Trace:         &trace,
+	})
+
+	body := `{"name":"trace","template_data":[{"variablestore":[{"name":"prefix","value":"gics"},{"name":"api_key","value":"secure-value","secure":true}]}],"template_repo":{"url":"https://github.com/johandry/gics","token":"git-token"}}`
+	req, _ := http.NewRequest("POST", url, strings.NewReader(body))
+	req.Header.Set("refresh_token", "request-refresh-token")
+	req.Header.Set("X-Github-token", "github-token")
+	resp, err := s.client.Client.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	data, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, `{"id":"trace-1a2b3c","refresh_token":"response-refresh-token"}`, string(data), "the response body should be readable after the trace")
+
+	out := trace.String()
+	for _, secret := range []string{"access-token", "request-refresh-token", "github-token", "secure-value", "git-token", "response-refresh-token"} {
+		assert.NotContains(t, out, secret)
+	}
+	for _, want := range []string{
+		"> POST " + url + "\n",
+		"> Authorization: [REDACTED]\n",
+		"> Refresh_token: [REDACTED]\n",
+		`{"name":"prefix","value":"gics"}`,
+		`{"name":"api_key","secure":true,"value":"[REDACTED]"}`,
+		"< 201 (",
+		"< Content-Type: application/json; charset=utf-8\n",
+		`"refresh_token":"[REDACTED]"`,
+	} {
+		assert.Contains(t, out, want)
+	}
+
+	trace.Reset()
+	req, _ = http.NewRequest("POST", url, io.MultiReader(strings.NewReader(body)))
+	if _, err := s.client.Client.Do(req); !assert.NoError(t, err) {
+		return
+	}
+	assert.Contains(t, trace.String(), "> [streamed body, not traced]\n")
+	assert.NotContains(t, trace.String(), "secure-value")
+}
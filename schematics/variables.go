@@ -0,0 +1,277 @@
+package schematics
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// VarOption is an option to set a variable with SetVar()
+type VarOption func(*Variable)
+
+// VarDescription sets the description of the variable
+func VarDescription(description string) VarOption {
+	return func(v *Variable) {
+		v.Description = description
+	}
+}
+
+// VarSecure sets the variable as sensitive, its value is hidden by Schematics
+func VarSecure() VarOption {
+	return func(v *Variable) {
+		v.Secure = true
+	}
+}
+
+// VarType sets the Terraform type of the variable instead of the type inferred
+// from the Go value, i.e. `set(string)`
+func VarType(varType string) VarOption {
+	return func(v *Variable) {
+		v.Type = varType
+	}
+}
+
+// SetVar sets the value of a variable from a Go value, replacing the variable
+// if it exists. The Terraform type is inferred from the Go type: bool is bool,
+// integers and floats are number, slices and arrays are list, maps with string
+// keys are map and structs are object, using the `json` tags for the attribute
+// names. Slices and maps of interface{} with values of different types are
+// tuple and object. The value is encoded in HCL as required by Schematics
+func (w *Workspace) SetVar(name string, v interface{}, opts ...VarOption) error {
+	if len(name) == 0 {
+		return fmt.Errorf("invalid variable name, it cannot be an empty string")
+	}
+
+	val, err := toCtyValue(reflect.ValueOf(v))
+	if err != nil {
+		return fmt.Errorf("invalid value for variable %q. %s", name, err)
+	}
+	if val.IsNull() {
+		return fmt.Errorf("invalid value for variable %q, it cannot be nil", name)
+	}
+
+	variable := Variable{
+		Name: name,
+		Type: typeString(val.Type()),
+	}
+	for _, opt := range opts {
+		opt(&variable)
+	}
+
+	if val.Type() == cty.String {
+		variable.Value = val.AsString()
+	} else {
+		variable.Value = strings.TrimSpace(string(hclwrite.TokensForValue(val).Bytes()))
+	}
+
+	for i := range w.Variables {
+		if w.Variables[i].Name == name {
+			w.Variables[i] = variable
+			return nil
+		}
+	}
+	w.Variables = append(w.Variables, variable)
+
+	return nil
+}
+
+// GetVar decodes the value of a variable into the given pointer, like
+// json.Unmarshal does. The value is decoded from HCL using the variable type,
+// so it works with the variables set with SetVar(), AddVar() or read from an
+// existing workspace
+func (w *Workspace) GetVar(name string, v interface{}) error {
+	for _, variable := range w.Variables {
+		if variable.Name == name {
+			return variable.Decode(v)
+		}
+	}
+	return fmt.Errorf("variable %q not found", name)
+}
+
+// Decode decodes the value of the variable into the given pointer, like
+// json.Unmarshal does
+func (v Variable) Decode(out interface{}) error {
+	ty, err := parseType(v.Type)
+	if err != nil {
+		return fmt.Errorf("invalid type %q of variable %q. %s", v.Type, v.Name, err)
+	}
+
+	var val cty.Value
+	if ty == cty.String || (ty == cty.DynamicPseudoType && !isHCLExpression(v.Value)) {
+		val = cty.StringVal(v.Value)
+	} else {
+		expr, diags := hclsyntax.ParseExpression([]byte(v.Value), v.Name, hcl.InitialPos)
+		if diags.HasErrors() {
+			return fmt.Errorf("invalid value of variable %q. %s", v.Name, diagsError(diags))
+		}
+		if val, diags = expr.Value(nil); diags.HasErrors() {
+			return fmt.Errorf("invalid value of variable %q. %s", v.Name, diagsError(diags))
+		}
+		if val, err = convert.Convert(val, ty); err != nil {
+			return fmt.Errorf("invalid value of variable %q, it's not a valid %s. %s", v.Name, v.Type, err)
+		}
+	}
+
+	data, err := ctyjson.Marshal(val, val.Type())
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// isHCLExpression returns true if the value of a variable without type looks
+// like a list or a map
+func isHCLExpression(value string) bool {
+	value = strings.TrimSpace(value)
+	return strings.HasPrefix(value, "[") || strings.HasPrefix(value, "{")
+}
+
+// toCtyValue converts a Go value into a cty value, the cty type is used to get
+// the Terraform type of the variable
+func toCtyValue(rv reflect.Value) (cty.Value, error) {
+	if !rv.IsValid() {
+		return cty.NullVal(cty.DynamicPseudoType), nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return cty.NullVal(toCtyType(rv.Type())), nil
+		}
+		return toCtyValue(rv.Elem())
+	case reflect.Bool:
+		return cty.BoolVal(rv.Bool()), nil
+	case reflect.String:
+		return cty.StringVal(rv.String()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cty.NumberIntVal(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cty.NumberVal(new(big.Float).SetUint64(rv.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return cty.NumberFloatVal(rv.Float()), nil
+	case reflect.Slice, reflect.Array:
+		if rv.Len() == 0 {
+			return cty.ListValEmpty(toCtyType(rv.Type().Elem())), nil
+		}
+		vals := make([]cty.Value, rv.Len())
+		for i := range vals {
+			val, err := toCtyValue(rv.Index(i))
+			if err != nil {
+				return cty.NilVal, err
+			}
+			vals[i] = val
+		}
+		if sameType(vals) {
+			return cty.ListVal(vals), nil
+		}
+		return cty.TupleVal(vals), nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return cty.NilVal, fmt.Errorf("map keys must be strings, found %s", rv.Type().Key())
+		}
+		if rv.Len() == 0 {
+			return cty.MapValEmpty(toCtyType(rv.Type().Elem())), nil
+		}
+		vals := map[string]cty.Value{}
+		for _, key := range rv.MapKeys() {
+			val, err := toCtyValue(rv.MapIndex(key))
+			if err != nil {
+				return cty.NilVal, err
+			}
+			vals[key.String()] = val
+		}
+		if sameType(mapValues(vals)) {
+			return cty.MapVal(vals), nil
+		}
+		return cty.ObjectVal(vals), nil
+	case reflect.Struct:
+		vals := map[string]cty.Value{}
+		for i := 0; i < rv.NumField(); i++ {
+			name, ok := fieldName(rv.Type().Field(i))
+			if !ok {
+				continue
+			}
+			val, err := toCtyValue(rv.Field(i))
+			if err != nil {
+				return cty.NilVal, err
+			}
+			vals[name] = val
+		}
+		return cty.ObjectVal(vals), nil
+	}
+
+	return cty.NilVal, fmt.Errorf("unsupported type %s", rv.Type())
+}
+
+// toCtyType returns the cty type of a Go type, used for the empty lists and
+// maps and for the nil values
+func toCtyType(rt reflect.Type) cty.Type {
+	switch rt.Kind() {
+	case reflect.Ptr:
+		return toCtyType(rt.Elem())
+	case reflect.Bool:
+		return cty.Bool
+	case reflect.String:
+		return cty.String
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return cty.Number
+	case reflect.Slice, reflect.Array:
+		return cty.List(toCtyType(rt.Elem()))
+	case reflect.Map:
+		return cty.Map(toCtyType(rt.Elem()))
+	case reflect.Struct:
+		attrs := map[string]cty.Type{}
+		for i := 0; i < rt.NumField(); i++ {
+			if name, ok := fieldName(rt.Field(i)); ok {
+				attrs[name] = toCtyType(rt.Field(i).Type)
+			}
+		}
+		return cty.Object(attrs)
+	}
+	return cty.DynamicPseudoType
+}
+
+// fieldName returns the attribute name of a struct field, from the `json` tag
+// or the field name. The unexported fields and the fields tagged `json:"-"`
+// are skipped
+func fieldName(f reflect.StructField) (string, bool) {
+	if len(f.PkgPath) != 0 {
+		return "", false
+	}
+	tag := strings.Split(f.Tag.Get("json"), ",")[0]
+	switch tag {
+	case "-":
+		return "", false
+	case "":
+		return f.Name, true
+	}
+	return tag, true
+}
+
+func sameType(vals []cty.Value) bool {
+	for _, val := range vals[1:] {
+		if !val.Type().Equals(vals[0].Type()) {
+			return false
+		}
+	}
+	return true
+}
+
+func mapValues(m map[string]cty.Value) []cty.Value {
+	vals := make([]cty.Value, 0, len(m))
+	for _, v := range m {
+		vals = append(vals, v)
+	}
+	return vals
+}
@@ -0,0 +1,94 @@
+package schematics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testNetwork struct {
+	Name    string   `json:"name"`
+	Public  bool     `json:"public"`
+	Zones   []string `json:"zones"`
+	private string
+}
+
+func TestWorkspace_SetVar(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     interface{}
+		wantType  string
+		wantValue string
+		wantErr   bool
+	}{
+		{"string", "gics", "string", "gics", false},
+		{"bool", true, "bool", "true", false},
+		{"int", 3, "number", "3", false},
+		{"float", 1.5, "number", "1.5", false},
+		{"list", []string{"us-south-1", "us-south-2"}, "list(string)", `["us-south-1", "us-south-2"]`, false},
+		{"empty list", []int{}, "list(number)", "[]", false},
+		{"map", map[string]string{"env": "dev"}, "map(string)", "{\n  env = \"dev\"\n}", false},
+		{"tuple", []interface{}{"a", 1}, "tuple([string,number])", `["a", 1]`, false},
+		{"object", map[string]interface{}{"name": "a", "count": 1}, "object({count=number,name=string})", "{\n  count = 1\n  name  = \"a\"\n}", false},
+		{"struct", &testNetwork{Name: "app", Zones: []string{"us-south-1"}}, "object({name=string,public=bool,zones=list(string)})", "{\n  name   = \"app\"\n  public = false\n  zones  = [\"us-south-1\"]\n}", false},
+		{"nil", nil, "", "", true},
+		{"int keys", map[int]string{1: "a"}, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := New("setvar", "", nil)
+			err := w.SetVar(tt.name, tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if !assert.NoError(t, err) || !assert.Len(t, w.Variables, 1) {
+				return
+			}
+			assert.Equal(t, tt.wantType, w.Variables[0].Type)
+			assert.Equal(t, tt.wantValue, w.Variables[0].Value)
+		})
+	}
+
+	w := New("setvar", "", nil)
+	w.SetVar("zones", []string{"us-south-1"})
+	w.SetVar("zones", []string{"us-south-2"}, VarDescription("the zones"), VarSecure())
+	assert.Equal(t, []Variable{{Name: "zones", Value: `["us-south-2"]`, Type: "list(string)", Description: "the zones", Secure: true}}, w.Variables)
+}
+
+func TestWorkspace_GetVar(t *testing.T) {
+	w := New("getvar", "", nil)
+	network := testNetwork{Name: "app", Public: true, Zones: []string{"us-south-1", "us-south-2"}}
+	w.SetVar("network", network)
+	w.SetVar("count", 3)
+	w.AddVar("tags", `{ env = "dev" }`, "map(string)", "", false)
+	w.AddVar("prefix", "gics", "", "", false)
+	w.AddVar("zones", `["us-south-1"]`, "", "", false)
+	w.AddVar("bad", `[1, 2`, "list(number)", "", false)
+
+	var gotNetwork testNetwork
+	if assert.NoError(t, w.GetVar("network", &gotNetwork)) {
+		assert.Equal(t, network, gotNetwork)
+	}
+	var gotCount int
+	if assert.NoError(t, w.GetVar("count", &gotCount)) {
+		assert.Equal(t, 3, gotCount)
+	}
+	var gotTags map[string]string
+	if assert.NoError(t, w.GetVar("tags", &gotTags)) {
+		assert.Equal(t, map[string]string{"env": "dev"}, gotTags)
+	}
+	var gotPrefix string
+	if assert.NoError(t, w.GetVar("prefix", &gotPrefix)) {
+		assert.Equal(t, "gics", gotPrefix)
+	}
+
+	w.Variables[4].Type = ""
+	var gotZones []string
+	if assert.NoError(t, w.GetVar("zones", &gotZones)) {
+		assert.Equal(t, []string{"us-south-1"}, gotZones)
+	}
+
+	assert.Error(t, w.GetVar("bad", &[]int{}))
+	assert.Error(t, w.GetVar("unknown", &gotPrefix))
+}
@@ -4,6 +4,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
 	"time"
 
 	apiv1 "github.com/johandry/gics/schematics/api/v1"
@@ -60,21 +64,43 @@ type Workspace struct {
 	CreatedAt           time.Time              `json:"created_at,omitempty" yaml:"created_at,omitempty"`
 	CreatedBy           string                 `json:"created_by,omitempty" yaml:"created_by,omitempty"`
 	Status              WorkspaceStatus        `json:"status,omitempty" yaml:"status,omitempty"`
+	StatusMessage       string                 `json:"status_message,omitempty" yaml:"status_message,omitempty"`
+	Locked              bool                   `json:"locked,omitempty" yaml:"locked,omitempty"`
+	LockedBy            string                 `json:"locked_by,omitempty" yaml:"locked_by,omitempty"`
+	Frozen              bool                   `json:"frozen,omitempty" yaml:"frozen,omitempty"`
 	Output              map[string]interface{} `json:"output,omitempty" yaml:"output,omitempty"`
-
+	// CodeCommit is the commit SHA of the local Git checkout the uploaded code
+	// was loaded from with LoadGitRef(), it's stored in the workspace tags
+	CodeCommit string `json:"code_commit,omitempty" yaml:"code_commit,omitempty"`
+	// SecretScan is what to do when a secret is found in the code or in the
+	// non-secure variables before they are sent, by default the action fails
+	SecretScan SecretScanMode `json:"secret_scan,omitempty" yaml:"secret_scan,omitempty"`
+
+	// Code is a Terraform code uploaded as `main.tf`, and TarCode is a pre-built
+	// tar (or tar.gz) archive with the code. They are used by Run() when there
+	// is no code loaded with the Load* methods, TarCode has precedence
 	Code    []byte
 	TarCode []byte
 
 	tfCodeFiles map[string]string
+	tfFileModes map[string]os.FileMode
+	excludes    []string
+	vendored    []VendoredModule
 	tfBuf       io.Reader
 	service     *Service
 	logOutput   io.Writer
 
+	codePath    string
+	codeCommit  string
+	templateID  string
+	repoFullURL string
+	// remoteCodeHash is the hash of the code uploaded to the workspace, it's
+	// stored in the workspace tags
+	remoteCodeHash string
+
 	// Other possible parameters used by the API:
 	// TemplateData         TemplateData                   `json:"template_data,omitempty" yaml:"template_data,omitempty"`
 	// Type                 []string                       `json:"type,omitempty" yaml:"type,omitempty"`
-	// WorkspaceStatus      *WorkspaceStatusResponse       `json:"workspace_status,omitempty"`
-	// WorkspaceStatusMsg   *WorkspaceStatusMessage        `json:"workspace_status_msg,omitempty"`
 	// Crn                  *string                        `json:"crn,omitempty"`
 	// LastHealthCheckAt    *time.Time                     `json:"last_health_check_at,omitempty"`
 	// RuntimeData          *[]TemplateRunTimeDataResponse `json:"runtime_data,omitempty"`
@@ -111,7 +137,7 @@ func New(name, description string, service *Service) *Workspace {
 	return &Workspace{
 		Name:        name,
 		Description: description,
-		Status:      WorkspaceStatus("NEW"),
+		Status:      WorkspaceStatusNew,
 		service:     service,
 		Type:        templateIDDefault,
 	}
@@ -167,39 +193,201 @@ func (w *Workspace) SetOutput(out io.Writer) {
 	w.logOutput = out
 }
 
+func (w *Workspace) logPrintf(format string, v ...interface{}) {
+	if w.logOutput == nil {
+		return
+	}
+
+	logger := log.New(w.logOutput, fmt.Sprintf("[%s]", w.ID), log.Ldate|log.Ltime|log.Lshortfile)
+	logger.Output(2, fmt.Sprintf(format, v...))
+}
+
 // LoadCode tar and loads the given code to the workspace
 func (w *Workspace) LoadCode(code string) error {
 	w.tfCodeFiles = map[string]string{
 		"main.tf": code,
 	}
+	w.tfFileModes = map[string]os.FileMode{}
+	w.codePath = ""
+	w.vendored = nil
+
+	return w.loadTar()
+}
+
+// LoadDir tar and loads all the files in the given directory to the workspace.
+// The local modules outside of the directory, with a relative source like
+// `../modules/network`, are copied into the code and their sources are
+// rewritten, see VendoredModules()
+func (w *Workspace) LoadDir(dir string) error {
+	files, modes, err := readDirFiles(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read the files in %q. %s", dir, err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("not found any file in %q", dir)
+	}
+	w.tfCodeFiles = files
+	w.tfFileModes = modes
+
+	if err := w.vendorModules(dir); err != nil {
+		return err
+	}
+	if err := w.loadTar(); err != nil {
+		return err
+	}
+
+	if absDir, err := filepath.Abs(dir); err == nil {
+		w.codePath = absDir
+	}
+
+	return nil
+}
+
+// LoadFS tar and loads all the files in the root directory of the given file
+// system to the workspace, i.e. a template embedded in the Go binary with
+// `//go:embed`, a zip archive opened with `zip.OpenReader` or a test fixture.
+// Use "." as root to load the entire file system
+func (w *Workspace) LoadFS(fsys fs.FS, root string) error {
+	if !fs.ValidPath(root) {
+		return fmt.Errorf("invalid root directory %q", root)
+	}
+	files, modes, err := readFSFiles(fsys, root)
+	if err != nil {
+		return fmt.Errorf("failed to read the files in %q. %s", root, err)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("not found any file in %q", root)
+	}
+	w.tfCodeFiles = files
+	w.tfFileModes = modes
+	w.codePath = ""
+	w.vendored = nil
+
+	return w.loadTar()
+}
+
+// LoadFiles tar and loads the given files to the workspace, replacing any code
+// already loaded. The files are indexed by their path, relative to the root of
+// the code, i.e. `main.tf` or `modules/network/main.tf`
+func (w *Workspace) LoadFiles(files map[string][]byte) error {
+	w.tfCodeFiles = map[string]string{}
+	w.tfFileModes = map[string]os.FileMode{}
+	w.codePath = ""
+	w.vendored = nil
+
+	for name, content := range files {
+		if err := w.addFile(name, content, 0); err != nil {
+			return err
+		}
+	}
+
+	return w.loadTar()
+}
+
+// AddFile adds a file to the code loaded in the workspace, replacing the file
+// if it exists. The path is relative to the root of the code, i.e.
+// `modules/network/main.tf`, and the mode sets the file permissions, i.e.
+// 0755 for an executable script. The default mode (0) is 0644
+func (w *Workspace) AddFile(path string, content []byte, mode os.FileMode) error {
+	if err := w.addFile(path, content, mode); err != nil {
+		return err
+	}
+	w.codePath = ""
+
+	return w.loadTar()
+}
+
+func (w *Workspace) addFile(path string, content []byte, mode os.FileMode) error {
+	name, err := codeFilePath(path)
+	if err != nil {
+		return err
+	}
+
+	if w.tfCodeFiles == nil {
+		w.tfCodeFiles = map[string]string{}
+	}
+	if w.tfFileModes == nil {
+		w.tfFileModes = map[string]os.FileMode{}
+	}
+
+	w.tfCodeFiles[name] = string(content)
+	delete(w.tfFileModes, name)
+	if mode != 0 && mode.Perm() != defaultFileMode {
+		w.tfFileModes[name] = mode.Perm()
+	}
+
+	return nil
+}
+
+// loadTar tar the code files in memory, ready to be uploaded. The excluded
+// files are removed from the code
+func (w *Workspace) loadTar() error {
+	w.excludeFiles()
+	w.codeCommit = ""
 
 	r, err := w.tarMemFiles()
 	if err != nil {
 		return fmt.Errorf("failed to tar the files in memory. %s", err)
 	}
-
 	w.tfBuf = r
 
 	return nil
 }
 
+// RunOptions are the parameters to modify the behavior of RunWithOptions
+type RunOptions struct {
+	// ReuseExisting finds the workspace by ID, or by name and resource group,
+	// and reuses it instead of creating a new one. The code is uploaded only if
+	// it has changed since the last upload
+	ReuseExisting bool
+}
+
 // Run is used to create, generate and apply the plan of the Schematics
 // workspace in a synchronous way, blocking the execution of the code until the
 // process is completed or fail
 func (w *Workspace) Run() error {
-	// Create the Schematics workspace
-	actCreate, err := w.Create()
-	if err != nil {
-		return err
-	}
-	// the activity should be a NilActivity, anyway we wait in case the API change
-	// in the future
-	if err := actCreate.Wait(); err != nil {
-		return err
+	return w.RunWithOptions(nil)
+}
+
+// RunWithOptions is like Run but with the given options. With ReuseExisting,
+// a retry after a failure continues from the plan of the existing workspace
+// instead of creating a new one
+func (w *Workspace) RunWithOptions(opt *RunOptions) error {
+	if opt == nil {
+		opt = &RunOptions{}
 	}
 
-	if err := w.UploadTar(w.tfBuf); err != nil {
-		return err
+	if opt.ReuseExisting {
+		// Reconcile creates the workspace if it does not exists, and uploads the
+		// code only if it has changed
+		if _, err := w.Reconcile(); err != nil {
+			return err
+		}
+		if err := w.WaitReady(); err != nil {
+			return err
+		}
+	} else {
+		if err := w.checkInputs(); err != nil {
+			return err
+		}
+
+		// Create the Schematics workspace
+		actCreate, err := w.Create()
+		if err != nil {
+			return err
+		}
+		// the activity should be a NilActivity, anyway we wait in case the API change
+		// in the future
+		if err := actCreate.Wait(); err != nil {
+			return err
+		}
+
+		if _, err := w.uploadCode(); err != nil {
+			return err
+		}
+		if err := w.WaitReady(); err != nil {
+			return err
+		}
 	}
 
 	// Generate the workspace plan
@@ -210,6 +398,9 @@ func (w *Workspace) Run() error {
 	if err := actPlan.Wait(); err != nil {
 		return err
 	}
+	if err := w.WaitReady(); err != nil {
+		return err
+	}
 
 	// Apply the workspace plan
 	actApply, err := w.Apply()
@@ -220,11 +411,16 @@ func (w *Workspace) Run() error {
 		return err
 	}
 
+	// Collect the outputs of the applied code
+	if _, err := w.Outputs(context.Background()); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 // GetParam collect and returns the requested output parameters of the execution
-// of the Schematics workspace
+// of the Schematics workspace. The outputs are loaded by Run() or Outputs()
 func (w *Workspace) GetParam(params ...string) map[string]interface{} {
 	output := map[string]interface{}{}
 	for _, key := range params {
@@ -260,7 +456,15 @@ func (w *Workspace) delete(destroy bool) error {
 	ctx, cancelFunc := context.WithTimeout(context.Background(), deleteWorkspaceTimeout*time.Second)
 	defer cancelFunc()
 
-	token, err := w.getToken()
+	action := WorkspaceActionDelete
+	if destroy {
+		action = WorkspaceActionDestroy
+	}
+	if err := w.checkAction(ctx, action); err != nil {
+		return err
+	}
+
+	token, err := w.service.refreshToken()
 	if err != nil {
 		return err
 	}
@@ -269,7 +473,7 @@ func (w *Workspace) delete(destroy bool) error {
 		DestroyResources: &destroy,
 		RefreshToken:     &token,
 	}
-	resp, err := w.service.clientWithResponses.DeleteWorkspaceWithResponse(ctx, w.ID, params)
+	resp, err := w.api().clientWithResponses.DeleteWorkspaceWithResponse(ctx, w.ID, params)
 	if err != nil {
 		return err
 	}
@@ -278,14 +482,8 @@ func (w *Workspace) delete(destroy bool) error {
 	}
 
 	// response := resp.JSON200 // *WorkspaceDeleteResponse => *String
-	// fmt.Printf("[DEBUG] Delete response: %+v\n", *response)
 
 	w.Status = WorkspaceStatusDeleted
 
 	return nil
 }
-
-// ListResources returns the list of resources created by the workspace
-func (w *Workspace) ListResources() ([]string, error) {
-	return []string{}, nil
-}